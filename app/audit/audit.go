@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/report"
+	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	outputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/output"
+	"github.com/rs/zerolog"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// AppType is the app type which determines if the app should run in audit mode.
+const AppType = "audit"
+
+// Run runs the functions to audit the metadata of the assets in iconik against the view,
+// writing a CSV report with a row for each asset and a summary of each field.
+func Run(cfg *config.App, outputSvc *outputsvc.Svc, l zerolog.Logger) error {
+	ctx, stop := signal.NotifyContext(l.WithContext(context.Background()), os.Interrupt)
+	defer stop()
+
+	q, err := outputsvc.NewQuery(cfg)
+	if err != nil {
+		return err
+	}
+	// collections hold no metadata worth auditing.
+	q.ObjectTypes = nil
+
+	r, err := report.New(ctx, cfg, outputSvc, cfg.Audit, "Audit", q)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to create report")
+		return err
+	}
+	defer r.Close()
+
+	views, err := outputSvc.GetMetadataViews(ctx, cfg.ViewIDs())
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to retrieve metadata view")
+		return err
+	}
+	view := metadatadomain.Merge(views...)
+
+	w := outputSvc.NewAuditWriter(r.CSV)
+	if err = w.WriteHeader(view.ViewFields); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write headers")
+		return err
+	}
+	// conditions are matched against values as an export would write them.
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return err
+	}
+	if err = outputSvc.ProcessPage(ctx, view.ViewFields, q, []interface{}{}, loc, w); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to audit assets")
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	if err = r.Finish(ctx, w.Audit()); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write audit")
+		return err
+	}
+
+	return nil
+}
@@ -5,66 +5,108 @@ import (
 	"errors"
 	"fmt"
 	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
 	inputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/input"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/storage"
 	"github.com/rs/zerolog"
+	"slices"
 )
 
 // AppType is the app type which determines if the app should run in input mode.
 const AppType = "input"
 
-// Run runs the functions to input data from a csv into iconik.
+// Run runs the functions to input data from a file into iconik.
 func Run(cfg *config.App, inputSvc *inputsvc.Svc, l zerolog.Logger) error {
-	fmt.Println("\nInputting data from provided CSV file...")
+	fmt.Println("\nInputting data from provided file...")
 
 	ctx := l.WithContext(context.Background())
 
-	view, err := inputSvc.GetMetadataView(ctx, cfg.ViewID)
+	// inputs from the standard input or an object store are read from a local copy.
+	local, cleanup, err := storage.Fetch(ctx, cfg.Input, cfg.S3())
 	if err != nil {
-		zerolog.Ctx(ctx).Err(err).Msg("failed to retrieve metadata view")
+		zerolog.Ctx(ctx).Err(err).Msg("failed to fetch input file")
 		return err
 	}
+	defer cleanup()
+	cfg.Input = local
 
-	csvData, err := inputSvc.ReadCSVFile(cfg)
+	views, err := inputSvc.GetMetadataViews(ctx, cfg.ViewIDs())
 	if err != nil {
-		zerolog.Ctx(ctx).Err(err).Msg("failed to read csv file")
+		zerolog.Ctx(ctx).Err(err).Msg("failed to retrieve metadata view")
 		return err
 	}
+	// columns are matched against the fields of every view, then written through their own view.
+	view := metadatadomain.Merge(views...)
 
-	csvHeaders := csvData[0]
-	if csvHeaders[0] != "id" || csvHeaders[1] != "original_name" || csvHeaders[2] != "size" || csvHeaders[3] != "title" {
-		fmt.Println(csvHeaders)
-		return errors.New("CSV file not properly formatted for Iconik")
+	var records []record.Record
+	var nonMatchingHeaders []string
+	switch cfg.FileFormat() {
+	case config.FormatJSON, config.FormatNDJSON, config.FormatXMP:
+		records, nonMatchingHeaders, err = readRecords(cfg, inputSvc, view)
+	default:
+		var data [][]string
+		data, err = inputSvc.ReadFile(cfg)
+		if err == nil && len(data) == 0 {
+			err = errors.New("input file is empty")
+		}
+		if err == nil && inputsvc.IsSegmentTable(data[0]) {
+			return runSegments(ctx, cfg, inputSvc, views, data)
+		}
+		if err == nil {
+			records, nonMatchingHeaders, err = readTable(inputSvc, view, data)
+		}
 	}
-
-	matchingData, nonMatchingHeaders, err := inputSvc.MatchCSVtoView(view.ViewFields, csvData)
 	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to read input file")
 		return err
 	}
 
-	if len(nonMatchingHeaders) > 0 {
-		fmt.Printf(`
-Some columns from the file provided have not been included in the upload to Iconik, 
-as they are not part of the metadata view provided. 
+	printNonMatching(nonMatchingHeaders)
 
-Please see below for the headers of the columns not included:
-`)
-		for _, nonMatchingHeader := range nonMatchingHeaders {
-			fmt.Println(nonMatchingHeader)
+	filesToUpdate := len(records)
+	fmt.Println("Amount of files to update:", filesToUpdate)
+
+	notAdded, err := inputSvc.ProcessRecords(ctx, records, cfg.CollectionID, views)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write records to iconik")
+		return err
+	}
+
+	fmt.Printf("Assets successfully updated: %d of %d\n", filesToUpdate-len(notAdded), filesToUpdate)
+	if len(notAdded) > 0 {
+		fmt.Println("Some assets failed to update:")
+		for assetID := range notAdded {
+			fmt.Printf("Asset ID: %s\n", assetID)
 		}
 	}
 
-	csvFilesToUpdate := len(matchingData) - 2
-	fmt.Println("Amount of files to update:", csvFilesToUpdate)
+	return nil
+}
+
+// runSegments writes the segments of a table with a row for each segment of an asset to iconik.
+func runSegments(ctx context.Context, cfg *config.App, inputSvc *inputsvc.Svc, views []metadatadomain.DTO, data [][]string) error {
+	view := metadatadomain.Merge(views...)
+
+	segs, nonMatchingHeaders, err := inputSvc.SegmentsFromTable(view.ViewFields, data, cfg.FrameRate())
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to read input file")
+		return err
+	}
+
+	printNonMatching(nonMatchingHeaders)
 
-	notAdded, err := inputSvc.ProcessAssets(ctx, matchingData, cfg.CollectionID, cfg.ViewID)
+	fmt.Println("Amount of segments to write:", len(segs))
+
+	created, updated, notAdded, err := inputSvc.ProcessSegments(ctx, segs, cfg.CollectionID, views)
 	if err != nil {
-		zerolog.Ctx(ctx).Err(err).Msg("failed to write csv to iconik")
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write segments to iconik")
 		return err
 	}
 
-	fmt.Printf("Assets successfully updated: %d of %d\n", csvFilesToUpdate-len(notAdded), csvFilesToUpdate)
+	fmt.Printf("Segments successfully created: %d, updated: %d\n", created, updated)
 	if len(notAdded) > 0 {
-		fmt.Println("Some assets failed to update:")
+		fmt.Println("Segments of some assets were not written:")
 		for assetID := range notAdded {
 			fmt.Printf("Asset ID: %s\n", assetID)
 		}
@@ -72,3 +114,62 @@ Please see below for the headers of the columns not included:
 
 	return nil
 }
+
+// printNonMatching lists the columns of the input file which are not in the metadata view.
+func printNonMatching(nonMatchingHeaders []string) {
+	if len(nonMatchingHeaders) == 0 {
+		return
+	}
+
+	fmt.Printf(`
+Some columns from the file provided have not been included in the upload to Iconik, 
+as they are not part of the metadata view provided. 
+
+Please see below for the headers of the columns not included:
+`)
+	for _, nonMatchingHeader := range nonMatchingHeaders {
+		fmt.Println(nonMatchingHeader)
+	}
+}
+
+// readTable matches the header labels of a CSV, xlsx or ALE input file, or its row of field
+// names if it has one, to the view. Tables in the long layout are pivoted into a record for
+// each asset.
+func readTable(inputSvc *inputsvc.Svc, view metadatadomain.DTO, csvData [][]string) ([]record.Record, []string, error) {
+	csvHeaders := csvData[0]
+	if inputsvc.IsLongTable(csvHeaders) {
+		return inputSvc.RecordsFromLongTable(view.ViewFields, csvData)
+	}
+
+	if !slices.Contains(csvHeaders, "id") && !slices.Contains(csvHeaders, "original_name") {
+		fmt.Println(csvHeaders)
+		return nil, nil, errors.New("CSV file not properly formatted for Iconik, it needs an id or original_name column")
+	}
+
+	matchingData, nonMatchingHeaders, err := inputSvc.MatchCSVtoView(view.ViewFields, csvData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, err := inputSvc.RecordsFromCSV(matchingData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return records, nonMatchingHeaders, nil
+}
+
+// readRecords reads a JSON, NDJSON or XMP input, and matches its field names to the view.
+func readRecords(cfg *config.App, inputSvc *inputsvc.Svc, view metadatadomain.DTO) ([]record.Record, []string, error) {
+	read := inputSvc.ReadJSONFile
+	if cfg.FileFormat() == config.FormatXMP {
+		read = inputSvc.ReadXMPFiles
+	}
+
+	records, err := read(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return inputSvc.MatchRecordsToView(view.ViewFields, records)
+}
@@ -0,0 +1,290 @@
+package input_test
+
+import (
+	"encoding/json"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/input"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/output"
+	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
+	assetsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/assets"
+	collsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/collections"
+	segmentsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/segments"
+	metadatasvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/metadata"
+	searchsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/search"
+	inputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/input"
+	outputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/output"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const viewID = "v1"
+
+// view is the metadata view of the fake iconik, with a field of each type the round trip covers.
+var view = map[string]interface{}{
+	"name": "Round trip",
+	"view_fields": []map[string]interface{}{
+		{"name": "rights_holder", "label": "Rights Holder", "field_type": "string"},
+		{"name": "tags", "label": "Tags", "field_type": "tag_cloud"},
+		{"name": "__separator__", "label": ""},
+		{"name": "signed", "label": "Signed off", "field_type": "boolean"},
+		{"name": "fr", "label": "Frame Rate", "field_type": "float"},
+		{"name": "takes", "label": "Takes", "field_type": "integer"},
+		{"name": "shot", "label": "Shot Date", "field_type": "date"},
+		{"name": "logged", "label": "Logged", "field_type": "datetime"},
+		{"name": "extra", "label": "Extra", "field_type": "string"},
+		{"name": "notes", "label": "Notes", "field_type": "text"},
+	},
+}
+
+// assets are the assets of the fake iconik, keyed by ID.
+var assets = map[string]map[string]interface{}{
+	"00000000-0000-0000-0000-000000000001": {
+		"title": "Asset 1",
+		"metadata": map[string]interface{}{
+			"rights_holder": []interface{}{"Smith, John"},
+			"tags":          []interface{}{"news", "a,b", `C:\clips`},
+			"signed":        []interface{}{true},
+			"fr":            []interface{}{29.97},
+			"takes":         []interface{}{3.0},
+			"shot":          []interface{}{"2024-03-01"},
+			"logged":        []interface{}{"2024-03-01T10:00:00Z"},
+			"extra":         []interface{}{map[string]interface{}{"a": 1.0, "b": []interface{}{"x, y", 2.0}}},
+		},
+	},
+	"00000000-0000-0000-0000-000000000002": {
+		"title": "Asset, 2",
+		"metadata": map[string]interface{}{
+			"rights_holder": []interface{}{"BBC"},
+			"tags":          []interface{}{"sport"},
+			"signed":        []interface{}{false},
+			"fr":            []interface{}{25.0},
+			"notes":         []interface{}{"Line one"},
+		},
+	},
+}
+
+// fakeIconik serves the search, views, metadata and asset endpoints the round trip uses,
+// recording the metadata and titles written to each asset.
+type fakeIconik struct {
+	mu       sync.Mutex
+	metadata map[string]map[string][]interface{}
+	titles   map[string]string
+}
+
+func (f *fakeIconik) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p := r.URL.Path
+
+	var out interface{}
+	switch {
+	case r.Method == http.MethodGet && p == iconik.MetadataViewPath+viewID+"/":
+		out = view
+	case r.Method == http.MethodGet && strings.HasPrefix(p, iconik.CollectionsPath):
+		out = map[string]string{"id": strings.Trim(strings.TrimPrefix(p, iconik.CollectionsPath), "/"), "title": "Rushes"}
+	case r.Method == http.MethodPost && p == iconik.SearchPath:
+		out = f.search(body)
+	case r.Method == http.MethodPut && strings.HasPrefix(p, iconik.MetadataAssetsPath):
+		id := strings.Split(strings.TrimPrefix(p, iconik.MetadataAssetsPath), "/")[0]
+		var values struct {
+			MetadataValues map[string]struct {
+				FieldValues []struct {
+					Value interface{} `json:"value"`
+				} `json:"field_values"`
+			} `json:"metadata_values"`
+		}
+		if err = json.Unmarshal(body, &values); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.metadata[id] = make(map[string][]interface{})
+		for name, fv := range values.MetadataValues {
+			for _, v := range fv.FieldValues {
+				f.metadata[id][name] = append(f.metadata[id][name], v.Value)
+			}
+		}
+		f.mu.Unlock()
+		out = view
+	case r.Method == http.MethodPatch && strings.HasPrefix(p, iconik.AssetsPath):
+		id := strings.Trim(strings.TrimPrefix(p, iconik.AssetsPath), "/")
+		var asset struct {
+			Title string `json:"title"`
+		}
+		if err = json.Unmarshal(body, &asset); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.titles[id] = asset.Title
+		f.mu.Unlock()
+		out = map[string]string{"id": id, "title": asset.Title}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// search returns every asset in a single page, or only the asset looked up by ID on input.
+func (f *fakeIconik) search(body []byte) map[string]interface{} {
+	var s struct {
+		Query       string        `json:"query"`
+		SearchAfter []interface{} `json:"search_after"`
+	}
+	_ = json.Unmarshal(body, &s)
+
+	objs := []interface{}{}
+	if len(s.SearchAfter) > 0 {
+		return map[string]interface{}{"objects": objs}
+	}
+	for i, id := range []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"} {
+		if s.Query != "" && s.Query != id {
+			continue
+		}
+		asset := assets[id]
+		objs = append(objs, map[string]interface{}{
+			"_sort":       []interface{}{i},
+			"id":          id,
+			"title":       asset["title"],
+			"object_type": "assets",
+			"metadata":    asset["metadata"],
+			"files":       []interface{}{map[string]interface{}{"original_name": "clip" + id[len(id)-1:] + ".mov", "size": 1000}},
+		})
+	}
+	return map[string]interface{}{"objects": objs, "total": len(objs)}
+}
+
+// newConfig returns the config of a run against the fake iconik at url.
+func newConfig(url string) *config.App {
+	return &config.App{
+		BaseURL:                url,
+		AppID:                  "app",
+		AuthToken:              "token",
+		CollectionID:           "c1",
+		ViewID:                 viewID,
+		Filename:               "{name}_Report_{timestamp}",
+		FPS:                    "25",
+		ObjectType:             "assets",
+		Columns:                "id,original_name,size,title",
+		Layout:                 "wide",
+		Timezone:               "UTC",
+		Delimiter:              "auto",
+		Encoding:               "auto",
+		OperationTimeout:       5 * time.Second,
+		OperationRetryAttempts: 1,
+		PerPage:                150,
+	}
+}
+
+// normalize returns values as they compare after a round trip through a file, which reads
+// every value back as a string.
+func normalize(values []interface{}) []string {
+	var s []string
+	for _, v := range values {
+		switch val := v.(type) {
+		case string:
+			s = append(s, val)
+		default:
+			b, _ := json.Marshal(val)
+			s = append(s, string(b))
+		}
+	}
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		layout     string
+		fieldNames bool
+	}{
+		{"csv", config.FormatCSV, "wide", false},
+		{"csv with field names", config.FormatCSV, "wide", true},
+		{"csv long", config.FormatCSV, "long", false},
+		{"xlsx", config.FormatXLSX, "wide", false},
+		{"xlsx with field names", config.FormatXLSX, "wide", true},
+		{"xlsx long", config.FormatXLSX, "long", false},
+		{"json", config.FormatJSON, "wide", false},
+		{"ndjson", config.FormatNDJSON, "wide", false},
+		{"ale", config.FormatALE, "wide", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeIconik{metadata: make(map[string]map[string][]interface{}), titles: make(map[string]string)}
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			dir := t.TempDir()
+			l := zerolog.Nop()
+
+			cfg := newConfig(srv.URL)
+			cfg.Type, cfg.Output, cfg.Format = output.AppType, dir+string(filepath.Separator), tt.format
+			cfg.Layout, cfg.FieldNames = tt.layout, tt.fieldNames
+			if err := output.Run(cfg, newOutputSvc(cfg), l); err != nil {
+				t.Fatalf("output: %v", err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(dir, "*."+tt.format))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("output wrote %v, want a single %s file", files, tt.format)
+			}
+
+			cfg = newConfig(srv.URL)
+			cfg.Type, cfg.Input, cfg.Format = input.AppType, files[0], tt.format
+			if err = input.Run(cfg, newInputSvc(cfg), l); err != nil {
+				t.Fatalf("input: %v", err)
+			}
+
+			for id, asset := range assets {
+				if got, want := fake.titles[id], asset["title"]; tt.layout == "wide" && got != want {
+					t.Errorf("%s: title = %q, want %q", id, got, want)
+				}
+
+				written, ok := fake.metadata[id]
+				if !ok {
+					t.Errorf("%s: no metadata written", id)
+					continue
+				}
+				metadata := asset["metadata"].(map[string]interface{})
+				for _, field := range view["view_fields"].([]map[string]interface{}) {
+					name := field["name"].(string)
+					if name == "__separator__" {
+						continue
+					}
+					want, _ := metadata[name].([]interface{})
+					if got := normalize(written[name]); !reflect.DeepEqual(got, normalize(want)) {
+						t.Errorf("%s: %s = %q, want %q", id, name, got, normalize(want))
+					}
+				}
+			}
+		})
+	}
+}
+
+func newOutputSvc(cfg *config.App) *outputsvc.Svc {
+	iconikAPI := iconik.New(cfg, api.New(&http.Client{}))
+	return outputsvc.New(collsvc.New(iconikAPI), metadatasvc.New(iconikAPI), searchsvc.New(iconikAPI), segmentsvc.New(iconikAPI))
+}
+
+func newInputSvc(cfg *config.App) *inputsvc.Svc {
+	iconikAPI := iconik.New(cfg, api.New(&http.Client{}))
+	return inputsvc.New(collsvc.New(iconikAPI), assetsvc.New(iconikAPI), metadatasvc.New(iconikAPI), searchsvc.New(iconikAPI), segmentsvc.New(iconikAPI))
+}
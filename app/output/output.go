@@ -2,55 +2,462 @@ package output
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/report"
 	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/ale"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
 	outputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/output"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/csvio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/storage"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xmlprofile"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xmp"
 	"github.com/rs/zerolog"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 )
 
 // AppType is the app type which determines if the app should run in output mode.
 const AppType = "output"
 
-// Run runs the functions to output data from iconik to a csv.
+// Run runs the functions to output data from iconik to a file.
 func Run(cfg *config.App, outputSvc *outputsvc.Svc, l zerolog.Logger) error {
-	ctx := l.WithContext(context.Background())
-	fmt.Println("Running output...")
+	// stop on interrupt, leaving the pages written so far in the file.
+	ctx, stop := signal.NotifyContext(l.WithContext(context.Background()), os.Interrupt)
+	defer stop()
 
-	view, err := outputSvc.GetMetadataView(ctx, cfg.ViewID)
+	dest, err := storage.NewSink(cfg.Output, cfg.S3())
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	// progress goes to the standard error when the export itself is piped out.
+	console := io.Writer(os.Stdout)
+	if cfg.Output == storage.Stdio {
+		console = os.Stderr
+	}
+	fmt.Fprintln(console, "Running output...")
+
+	views, err := outputSvc.GetMetadataViews(ctx, cfg.ViewIDs())
 	if err != nil {
 		zerolog.Ctx(ctx).Err(err).Msg("failed to retrieve metadata view")
 		return err
 	}
+	// several views are exported as one, with the fields they share written once.
+	view := metadatadomain.Merge(views...)
 
-	coll, err := outputSvc.GetCollection(ctx, cfg.CollectionID)
+	values, title, err := report.Names(ctx, outputSvc, cfg.CollectionIDs())
 	if err != nil {
 		zerolog.Ctx(ctx).Err(err).Msg("failed to retrieve collection")
 		return err
 	}
 
-	filePath := cfg.Output + fmt.Sprintf("%s_%s_Report_%s.csv", cfg.CollectionID, coll.Title, time.Now().Format("2006-01-02_150405"))
+	format := cfg.FileFormat()
+	filePath := dest.Path(report.FileName(cfg.Filename, values))
 
-	f, err := os.Create(filePath)
+	q, err := outputsvc.NewQuery(cfg)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	w := csv.NewWriter(f)
-	if err = w.WriteAll(outputSvc.Headers(view.ViewFields)); err != nil {
-		zerolog.Ctx(ctx).Err(err).Msg("failed to write headers to csv")
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
 		return err
 	}
 
-	if err = outputSvc.ProcessPage(ctx, view.ViewFields, cfg.CollectionID, []interface{}{}, w); err != nil {
-		zerolog.Ctx(ctx).Err(err).Msg("failed to write assets to csv")
+	layout := outputsvc.Layout{FileRows: cfg.FileRows, Timezone: cfg.Timezone, FieldNames: cfg.FieldNames}
+	if cfg.Layout == "long" {
+		if format != config.FormatCSV && format != config.FormatXLSX {
+			return fmt.Errorf("the long layout can't be written as %s, only as csv or xlsx", format)
+		}
+		layout.Long, layout.Collections = true, slices.Contains(q.ObjectTypes, "collections")
+	}
+	if layout.Columns, err = outputsvc.ParseColumns(cfg.Columns); err != nil {
+		return err
+	}
+
+	// labels shared by several fields can't be matched back on input, so write the names too.
+	if dup := outputSvc.DuplicateLabels(view.ViewFields); len(dup) > 0 && !layout.Long {
+		switch format {
+		case config.FormatCSV, config.FormatXLSX:
+			if !layout.FieldNames {
+				fmt.Fprintf(console, "The view has fields sharing the labels %s, writing a row of field names under the header\n", strings.Join(dup, ", "))
+				layout.FieldNames = true
+			}
+		case config.FormatALE:
+			fmt.Fprintf(console, "The view has fields sharing the labels %s, which can't be read back from an ALE file\n", strings.Join(dup, ", "))
+		}
+	}
+
+	started := time.Now().UTC()
+	incremental := cfg.Since != ""
+	lastRun := outputsvc.LastRunPath(filepath.Dir(filePath), q, cfg.ViewID)
+	if q.ModifiedSince, err = since(cfg.Since, lastRun); err != nil {
 		return err
 	}
 
-	fmt.Println("Output complete. CSV created at " + filePath)
+	var state *outputsvc.State
+	if cfg.Resume != "" {
+		if state, err = outputsvc.LoadState(cfg.Resume + outputsvc.StateExt); err != nil {
+			return err
+		}
+		if state.ViewID != cfg.ViewID {
+			return fmt.Errorf("%s is an export with view %s", cfg.Resume, state.ViewID)
+		}
+		// the search is resumed as it was saved, whatever the search flags are now.
+		q, started, incremental = state.Query, state.Started, state.Incremental
+		lastRun = outputsvc.LastRunPath(filepath.Dir(cfg.Resume), q, state.ViewID)
+		format, layout = state.Format, state.Layout
+		filePath = cfg.Resume
+		fmt.Fprintf(console, "Resuming export after %d assets...\n", state.Rows)
+	}
+
+	if layout.HasColumn(outputsvc.CollectionPathColumn) || cfg.SubcollectionFiles {
+		layout = layout.WithCollectionPaths(outputSvc.NewCollectionPaths(ctx, q.CollectionIDs))
+	}
+
+	if !q.ModifiedSince.IsZero() {
+		verb := "modified"
+		if q.Deleted {
+			verb = "deleted"
+		}
+		fmt.Fprintf(console, "Exporting assets %s since %s\n", verb, q.ModifiedSince.Format(time.RFC3339))
+	}
+
+	if cfg.Output == storage.Stdio {
+		switch {
+		case cfg.SubcollectionFiles || cfg.Parts() || format == config.FormatXMP || format == config.FormatTranscripts,
+			(format == config.FormatEBUCore || format == config.FormatPBCore) && !cfg.PerCollection:
+			return errors.New("only exports of a single file can be written to the standard output")
+		}
+	}
+
+	if cfg.Parts() {
+		switch {
+		case format == config.FormatXMP || format == config.FormatEBUCore || format == config.FormatPBCore || format == config.FormatTranscripts:
+			return fmt.Errorf("%s exports can't be split or compressed", format)
+		case format == config.FormatXLSX && cfg.SplitMB > 0:
+			return errors.New("xlsx exports can't be split by size, use -split-rows instead")
+		}
+	}
+
+	var w outputsvc.Writer
+	switch {
+	case cfg.SubcollectionFiles:
+		if format == config.FormatXMP || format == config.FormatEBUCore || format == config.FormatPBCore || format == config.FormatTranscripts {
+			return fmt.Errorf("-subcollection-files can't split %s exports", format)
+		}
+		dir := filePath
+		w = outputSvc.NewTreeWriter(layout.CollectionPaths(), func(path []string) string {
+			return treeFileName(dir, path, format)
+		}, func(name string, appending bool) (outputsvc.Writer, error) {
+			return openTreeFile(ctx, cfg, outputSvc, format, layout, name, appending, view.ViewFields)
+		}, resumable(format))
+	case format == config.FormatXMP:
+		mapping, err := xmp.LoadMapping(cfg.XMPMapping)
+		if err != nil {
+			return err
+		}
+		w = outputSvc.NewXMPWriter(filePath, mapping, loc)
+	case cfg.Parts():
+		files, err := outputsvc.NewFiles(filePath, format, cfg.Compress, cfg.SplitRows > 0 || cfg.SplitMB > 0)
+		if err != nil {
+			return err
+		}
+		w = outputSvc.NewSplitWriter(files, func(f io.Writer) (outputsvc.Writer, error) {
+			return newWriter(ctx, cfg, outputSvc, format, layout, f, view.ViewFields, false)
+		}, cfg.SplitRows, int64(cfg.SplitMB)<<20)
+	case format == config.FormatTranscripts:
+		w = outputSvc.NewTranscriptWriter(ctx, filePath, cfg.TranscriptText)
+	case format == config.FormatEBUCore || format == config.FormatPBCore:
+		profile := format
+		if cfg.Profile != "" {
+			profile = cfg.Profile
+		}
+		p, err := xmlprofile.Load(profile)
+		if err != nil {
+			return err
+		}
+		if cfg.PerCollection {
+			filePath += ".xml"
+		}
+		if w, err = outputSvc.NewXMLWriter(filePath, p, title, cfg.PerCollection, loc); err != nil {
+			return err
+		}
+	default:
+		if state == nil {
+			filePath += "." + format
+		}
+
+		// a single file is piped straight to the standard output, rather than written first.
+		if cfg.Output == storage.Stdio {
+			if w, err = newWriter(ctx, cfg, outputSvc, format, layout, os.Stdout, view.ViewFields, false); err != nil {
+				return err
+			}
+			break
+		}
+
+		f, err := openFile(filePath, state)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w, err = newWriter(ctx, cfg, outputSvc, format, layout, f, view.ViewFields, state != nil)
+		if err != nil {
+			return err
+		}
+
+		if resumable(format) && !cfg.Segments {
+			if state == nil {
+				state = &outputsvc.State{Query: q, ViewID: cfg.ViewID, Format: format, Layout: layout, Started: started, Incremental: incremental}
+			}
+			w = outputSvc.NewResumableWriter(w, state, filePath+outputsvc.StateExt, func() (int64, error) {
+				return f.Seek(0, io.SeekCurrent)
+			})
+		}
+	}
+
+	searchAfter := []interface{}{}
+	if cfg.Resume != "" {
+		searchAfter = state.SearchAfter
+	} else if err = w.WriteHeader(view.ViewFields); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write headers")
+		return err
+	}
+
+	if err = outputSvc.ProcessPage(ctx, view.ViewFields, q, searchAfter, layout.Location(), w); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write assets")
+		// close the writer anyway, so formats with a footer are still valid.
+		_ = w.Close()
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write file")
+		return err
+	}
+
+	if resumable(format) && !cfg.SubcollectionFiles && !cfg.Segments && cfg.Output != storage.Stdio {
+		if err = os.Remove(filePath + outputsvc.StateExt); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// the last run is kept next to local exports only, as it is read back from there.
+	if incremental && !dest.Remote() {
+		if err = outputsvc.SaveLastRun(lastRun, started); err != nil {
+			return err
+		}
+	}
+
+	if err = dest.Deliver(ctx); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to deliver export")
+		return err
+	}
+
+	if tw, ok := w.(*outputsvc.TranscriptWriter); ok {
+		written, skipped := tw.Counts()
+		fmt.Fprintf(console, "Transcripts written: %d, assets without a transcript: %d\n", written, skipped)
+	}
+
+	if sw, ok := w.(*outputsvc.SplitWriter); ok {
+		m := sw.Manifest()
+		fmt.Fprintf(console, "Output complete. %d rows written to %d files, listed in %s\n", m.Rows, len(m.Parts), dest.Location(filePath+outputsvc.ManifestExt))
+		return nil
+	}
+
+	if cfg.SubcollectionFiles || format == config.FormatTranscripts {
+		fmt.Fprintln(console, "Output complete. Files created under "+dest.Location(filePath))
+	} else {
+		fmt.Fprintln(console, "Output complete. File created at "+dest.Location(filePath))
+	}
 
 	return nil
 }
+
+// since returns the time an incremental export starts from, which is either an RFC 3339 time
+// or "last" for the start of the last incremental export. It returns the zero time for a full
+// export, including the first incremental export.
+func since(value, lastRunPath string) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "last":
+		return outputsvc.LoadLastRun(lastRunPath)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since time %s, expected RFC 3339 such as 2024-01-31T00:00:00Z or last", value)
+	}
+
+	return t, nil
+}
+
+// newSegmentWriter returns the writer of a segment export, which is written as csv or xlsx. When
+// appending, nothing is written before the first row.
+func newSegmentWriter(ctx context.Context, cfg *config.App, outputSvc *outputsvc.Svc, format string, layout outputsvc.Layout, f io.Writer, viewFields []metadatadomain.ViewFieldDTO, appending bool) (outputsvc.Writer, error) {
+	var fps float64
+	if cfg.Timecode {
+		fps = cfg.FrameRate()
+	}
+
+	var types []string
+	for _, t := range strings.Split(cfg.SegmentTypes, ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	var rw outputsvc.RowWriter
+	switch format {
+	case config.FormatCSV:
+		d := cfg.CSVDialect()
+		d.Append = appending
+		cw, err := csvio.NewWriter(f, d)
+		if err != nil {
+			return nil, err
+		}
+		rw = cw
+	case config.FormatXLSX:
+		xw, err := xlsxio.NewWriter(f, outputSvc.SegmentColumns(layout, viewFields, fps))
+		if err != nil {
+			return nil, err
+		}
+		if layout.FieldNames {
+			xw.HeaderRows = 2
+		}
+		rw = xw
+	default:
+		return nil, fmt.Errorf("segments can't be written as %s, only as csv or xlsx", format)
+	}
+
+	return outputSvc.NewSegmentWriter(ctx, rw, layout, types, fps), nil
+}
+
+// resumable reports whether exports in format can be resumed, which needs a format that can be
+// appended to without rewriting a header or footer.
+func resumable(format string) bool {
+	return format == config.FormatCSV || format == config.FormatNDJSON
+}
+
+// openFile creates the output file, or opens the file being resumed and truncates any partly
+// written page after the last saved state.
+func openFile(path string, state *outputsvc.State) (*os.File, error) {
+	if state == nil {
+		return os.Create(path)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Truncate(state.Offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(state.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// treeFile is the writer of a subcollection's file, which closes the file with the writer.
+type treeFile struct {
+	outputsvc.Writer
+	f *os.File
+}
+
+// Close closes the writer and its file.
+func (tf *treeFile) Close() error {
+	err := tf.Writer.Close()
+	if cerr := tf.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// treeFileName returns the file of a subcollection, in folders under dir named after the
+// collections of its path. Each file is named after its own collection, and assets outside the
+// collections searched are written to a file in dir.
+func treeFileName(dir string, path []string, format string) string {
+	name := filepath.Base(dir)
+	for _, title := range path {
+		name = report.PathSegment(title)
+		dir = filepath.Join(dir, name)
+	}
+
+	return filepath.Join(dir, name+"."+format)
+}
+
+// openTreeFile creates the file of a subcollection, or opens it to append to, and returns a
+// writer for it.
+func openTreeFile(ctx context.Context, cfg *config.App, outputSvc *outputsvc.Svc, format string, layout outputsvc.Layout, name string, appending bool, viewFields []metadatadomain.ViewFieldDTO) (outputsvc.Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return nil, err
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appending {
+		flag = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(name, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWriter(ctx, cfg, outputSvc, format, layout, f, viewFields, appending)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &treeFile{Writer: w, f: f}, nil
+}
+
+// newWriter returns the writer for the selected output format. When appending, nothing is
+// written before the first row.
+func newWriter(ctx context.Context, cfg *config.App, outputSvc *outputsvc.Svc, format string, layout outputsvc.Layout, f io.Writer, viewFields []metadatadomain.ViewFieldDTO, appending bool) (outputsvc.Writer, error) {
+	if cfg.Segments {
+		return newSegmentWriter(ctx, cfg, outputSvc, format, layout, f, viewFields, appending)
+	}
+
+	switch format {
+	case config.FormatCSV:
+		d := cfg.CSVDialect()
+		d.Append = appending
+		cw, err := csvio.NewWriter(f, d)
+		if err != nil {
+			return nil, err
+		}
+		return outputSvc.NewTableWriter(cw, layout), nil
+	case config.FormatXLSX:
+		xw, err := xlsxio.NewWriter(f, outputSvc.Columns(layout, viewFields))
+		if err != nil {
+			return nil, err
+		}
+		if layout.FieldNames {
+			xw.HeaderRows = 2
+		}
+		return outputSvc.NewTableWriter(xw, layout), nil
+	case config.FormatJSON:
+		return outputSvc.NewRecordWriter(f, false), nil
+	case config.FormatNDJSON:
+		return outputSvc.NewRecordWriter(f, true), nil
+	case config.FormatALE:
+		return outputSvc.NewALEWriter(f, ale.Heading{VideoFormat: cfg.ALEVideoFormat, AudioFormat: cfg.ALEAudioFormat, FPS: cfg.FPS}, layout), nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format %s", format)
+}
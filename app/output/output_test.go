@@ -0,0 +1,31 @@
+package output
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeFileName(t *testing.T) {
+	dir := filepath.Join("out", "c1_Series_Report")
+
+	tests := []struct {
+		name string
+		path []string
+		want string
+	}{
+		{"outside the search", nil, filepath.Join(dir, "c1_Series_Report.csv")},
+		{"subcollection", []string{"Series", "Episode 1"}, filepath.Join(dir, "Series", "Episode 1", "Episode 1.csv")},
+		{"slash", []string{"Series", "A/B"}, filepath.Join(dir, "Series", "A_B", "A_B.csv")},
+		// a title which only differs from another in a slash shares its file.
+		{"underscore", []string{"Series", "A_B"}, filepath.Join(dir, "Series", "A_B", "A_B.csv")},
+		{"parent folder", []string{"Series", ".."}, filepath.Join(dir, "Series", "_", "_.csv")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := treeFileName(dir, tt.path, "csv"); got != tt.want {
+				t.Errorf("treeFileName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Package report names the files written by the output, audit and stats modes, and writes the
+CSV report and summary of the audit and stats modes.
+*/
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	outputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/output"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/csvio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/storage"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Names returns the values of the -filename placeholders for an export of the collections ids,
+// and the title of the export. A single collection names the export, otherwise it is named
+// after the search.
+func Names(ctx context.Context, svc *outputsvc.Svc, ids []string) (map[string]string, string, error) {
+	name, title := "search", "Search results"
+	collectionID, collectionTitle := "", ""
+	switch {
+	case len(ids) == 1:
+		coll, err := svc.GetCollection(ctx, ids[0])
+		if err != nil {
+			return nil, "", err
+		}
+		name, title = ids[0]+"_"+coll.Title, coll.Title
+		collectionID, collectionTitle = ids[0], coll.Title
+	case len(ids) > 1:
+		name = fmt.Sprintf("%d_collections", len(ids))
+	}
+
+	return map[string]string{
+		"name":             name,
+		"collection_id":    collectionID,
+		"collection_title": collectionTitle,
+		"timestamp":        time.Now().Format("2006-01-02_150405"),
+	}, title, nil
+}
+
+// FileName expands the placeholders of the -filename template. Their values are sanitised as
+// path segments, so a collection title can't add folders to the path or name a parent folder.
+func FileName(template string, values map[string]string) string {
+	var oldnew []string
+	for k, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			v = PathSegment(v)
+		}
+		oldnew = append(oldnew, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(oldnew...).Replace(template)
+}
+
+// PathSegment returns a collection title as a file or folder name.
+func PathSegment(title string) string {
+	title = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(title))
+	if title == "" || title == "." || title == ".." {
+		return "_"
+	}
+	return title
+}
+
+// Summary is the outcome of an audit or stats run, written as a human readable summary.
+type Summary interface {
+	WriteSummary(w io.Writer) error
+}
+
+// Report is the CSV report and summary of an audit or stats run, delivered to a folder or file,
+// an object store, or the standard output. The summary is written to a text file next to the
+// report, and printed once the report is delivered.
+type Report struct {
+	// Console is where progress and the summary are printed, which is the standard error when
+	// the report itself is piped out.
+	Console io.Writer
+	// CSV is the writer of the report rows.
+	CSV *csv.Writer
+
+	kind   string
+	stdout bool
+	dest   storage.Sink
+	path   string
+	f      *os.File
+}
+
+// New returns a new Report of kind, such as Audit or Stats, for the search q, delivered to
+// target under a name built from the -filename template.
+func New(ctx context.Context, cfg *config.App, svc *outputsvc.Svc, target, kind string, q outputsvc.Query) (*Report, error) {
+	dest, err := storage.NewSink(target, cfg.S3())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Console: os.Stdout, kind: kind, stdout: target == storage.Stdio, dest: dest}
+	if r.stdout {
+		r.Console = os.Stderr
+	}
+	fmt.Fprintf(r.Console, "Running %s...\n", strings.ToLower(kind))
+
+	values, _, err := Names(ctx, svc, q.CollectionIDs)
+	if err != nil {
+		dest.Close()
+		return nil, err
+	}
+	values["name"] += "_" + kind
+	r.path = dest.Path(FileName(cfg.Filename, values))
+
+	// the standard output takes the report straight away, so it can be piped.
+	out := io.Writer(os.Stdout)
+	if !r.stdout {
+		if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+			dest.Close()
+			return nil, err
+		}
+		if r.f, err = os.Create(r.path + ".csv"); err != nil {
+			dest.Close()
+			return nil, err
+		}
+		out = r.f
+	}
+
+	if r.CSV, err = csvio.NewWriter(out, cfg.CSVDialect()); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Finish writes the summary of the report, delivers both, and prints the summary. The standard
+// output takes a single file, so the summary is only printed when the report is piped out.
+func (r *Report) Finish(ctx context.Context, s Summary) error {
+	r.CSV.Flush()
+	if err := r.CSV.Error(); err != nil {
+		return err
+	}
+	if r.f != nil {
+		if err := r.f.Close(); err != nil {
+			return err
+		}
+	}
+
+	var summary strings.Builder
+	if err := s.WriteSummary(&summary); err != nil {
+		return err
+	}
+	if !r.stdout {
+		if err := os.WriteFile(r.path+".txt", []byte(summary.String()), 0o644); err != nil {
+			return err
+		}
+	}
+
+	if err := r.dest.Deliver(ctx); err != nil {
+		return fmt.Errorf("failed to deliver %s: %w", strings.ToLower(r.kind), err)
+	}
+
+	fmt.Fprint(r.Console, "\n"+summary.String()+"\n")
+	if !r.stdout {
+		fmt.Fprintf(r.Console, "%s complete. Report created at %s, summary at %s\n", r.kind, r.dest.Location(r.path+".csv"), r.dest.Location(r.path+".txt"))
+	}
+
+	return nil
+}
+
+// Close closes the report file if it is still open, and removes anything written only to be
+// delivered.
+func (r *Report) Close() error {
+	if r.f != nil {
+		r.f.Close()
+	}
+	return r.dest.Close()
+}
@@ -0,0 +1,28 @@
+package report
+
+import "testing"
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{"title", map[string]string{"name": "c1", "collection_title": "Rushes"}, "c1_Rushes"},
+		{"slashes", map[string]string{"name": "c1", "collection_title": "Reels/Rushes\\B"}, "c1_Reels_Rushes_B"},
+		{"current folder", map[string]string{"name": "c1", "collection_title": " . "}, "c1__"},
+		{"parent folder", map[string]string{"name": "c1", "collection_title": ".."}, "c1__"},
+		{"empty value", map[string]string{"name": "c1", "collection_title": ""}, "c1_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FileName("{name}_{collection_title}", tt.values); got != tt.want {
+				t.Errorf("FileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := FileName("{collection_title}", map[string]string{"collection_title": ".."}); got != "_" {
+		t.Errorf("FileName() = %q, want a name inside the output folder", got)
+	}
+}
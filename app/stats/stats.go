@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"context"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/report"
+	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	outputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/output"
+	"github.com/rs/zerolog"
+	"os"
+	"os/signal"
+)
+
+// AppType is the app type which determines if the app should run in stats mode.
+const AppType = "stats"
+
+// Run runs the functions to count the assets in iconik by their facet values and total their
+// file sizes by media type, writing a CSV report and a summary.
+func Run(cfg *config.App, outputSvc *outputsvc.Svc, l zerolog.Logger) error {
+	ctx, stop := signal.NotifyContext(l.WithContext(context.Background()), os.Interrupt)
+	defer stop()
+
+	q, err := outputsvc.NewQuery(cfg)
+	if err != nil {
+		return err
+	}
+	// the library composition is that of its assets.
+	q.ObjectTypes = nil
+
+	r, err := report.New(ctx, cfg, outputSvc, cfg.Stats, "Stats", q)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to create report")
+		return err
+	}
+	defer r.Close()
+
+	stats, err := outputSvc.Stats(ctx, q)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to count assets")
+		return err
+	}
+	if err = r.CSV.WriteAll(stats.Rows()); err != nil {
+		return err
+	}
+
+	if err = r.Finish(ctx, stats); err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("failed to write stats")
+		return err
+	}
+
+	return nil
+}
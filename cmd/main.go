@@ -2,14 +2,17 @@ package main
 
 import (
 	"fmt"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/audit"
 	"github.com/base-media-cloud/pd-iconik-io-rd/app/input"
 	"github.com/base-media-cloud/pd-iconik-io-rd/app/output"
+	"github.com/base-media-cloud/pd-iconik-io-rd/app/stats"
 	"github.com/base-media-cloud/pd-iconik-io-rd/config"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain"
 	assetsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/assets"
 	collsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/collections"
+	segmentsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/assets/segments"
 	metadatasvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/metadata"
 	searchsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/iconik/search"
 	inputsvc "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/services/input"
@@ -45,9 +48,10 @@ func main() {
 	collSvc := collsvc.New(iconikAPI)
 	metadataSvc := metadatasvc.New(iconikAPI)
 	searchSvc := searchsvc.New(iconikAPI)
+	segmentSvc := segmentsvc.New(iconikAPI)
 
 	if cfg.Type == input.AppType {
-		inputSvc := inputsvc.New(collSvc, assetSvc, metadataSvc, searchSvc)
+		inputSvc := inputsvc.New(collSvc, assetSvc, metadataSvc, searchSvc, segmentSvc)
 		if err = input.Run(cfg, inputSvc, l); err != nil {
 			fmt.Println(err)
 			l.Fatal().Err(err).Msg("error running input mode")
@@ -55,8 +59,26 @@ func main() {
 		return
 	}
 
+	if cfg.Type == audit.AppType {
+		outputSvc := outputsvc.New(collSvc, metadataSvc, searchSvc, segmentSvc)
+		if err = audit.Run(cfg, outputSvc, l); err != nil {
+			fmt.Println(err)
+			l.Fatal().Err(err).Msg("error running audit mode")
+		}
+		return
+	}
+
+	if cfg.Type == stats.AppType {
+		outputSvc := outputsvc.New(collSvc, metadataSvc, searchSvc, segmentSvc)
+		if err = stats.Run(cfg, outputSvc, l); err != nil {
+			fmt.Println(err)
+			l.Fatal().Err(err).Msg("error running stats mode")
+		}
+		return
+	}
+
 	if cfg.Type == output.AppType {
-		outputSvc := outputsvc.New(collSvc, metadataSvc, searchSvc)
+		outputSvc := outputsvc.New(collSvc, metadataSvc, searchSvc, segmentSvc)
 		if err = output.Run(cfg, outputSvc, l); err != nil {
 			fmt.Println(err)
 			l.Fatal().Err(err).Msg("error running output mode")
@@ -5,28 +5,100 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+	// bundled so timezones are known on systems without a timezone database.
+	_ "time/tzdata"
 
 	"github.com/sethvargo/go-envconfig"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/csvio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/storage"
 )
 
 var version string
 
+const (
+	// FormatCSV is the format of CSV files.
+	FormatCSV = "csv"
+	// FormatXLSX is the format of Excel workbooks.
+	FormatXLSX = "xlsx"
+	// FormatJSON is the format of JSON files holding an array of records.
+	FormatJSON = "json"
+	// FormatNDJSON is the format of newline delimited JSON files holding one record per line.
+	FormatNDJSON = "ndjson"
+	// FormatALE is the format of Avid Log Exchange files.
+	FormatALE = "ale"
+	// FormatXMP is the format of folders of XMP sidecar files, one per asset.
+	FormatXMP = "xmp"
+	// FormatEBUCore is the format of EBUCore XML documents.
+	FormatEBUCore = "ebucore"
+	// FormatPBCore is the format of PBCore XML documents.
+	FormatPBCore = "pbcore"
+	// FormatTranscripts is the format of folders of SRT and WebVTT subtitle files, one of each
+	// per asset.
+	FormatTranscripts = "transcripts"
+)
+
 // App is a struct that represents the app config.
 type App struct {
 	Type                   string
 	Input                  string
 	Output                 string
+	Audit                  string
+	Stats                  string
+	Filename               string
 	BaseURL                string
 	AppID                  string
 	AuthToken              string
 	CollectionID           string
 	ViewID                 string
+	Format                 string
+	Sheet                  string
+	FPS                    string
+	ALEVideoFormat         string
+	ALEAudioFormat         string
+	XMPMapping             string
+	Profile                string
+	PerCollection          bool
+	TranscriptText         bool
+	ObjectType             string
+	Segments               bool
+	SegmentTypes           string
+	Timecode               bool
+	SubcollectionFiles     bool
+	SplitRows              int
+	SplitMB                int
+	Compress               string
+	Resume                 string
+	Since                  string
+	Query                  string
+	Filters                Values
+	Where                  Values
+	Sort                   string
+	Columns                string
+	FileRows               bool
+	FieldNames             bool
+	Layout                 string
+	Timezone               string
+	Deleted                bool
+	Delimiter              string
+	Encoding               string
+	LazyQuotes             bool
+	Excel                  bool
 	OperationTimeout       time.Duration `env:"OPERATION_TIMEOUT,default=30s"`
 	OperationRetryAttempts uint          `env:"OPERATION_RETRY_ATTEMPTS,default=1"`
 	OperationRetryDelay    time.Duration `env:"OPERATION_RETRY_DELAY,default=3s"`
 	PerPage                int           `env:"PER_PAGE,default=150"`
+	S3Endpoint             string        `env:"S3_ENDPOINT"`
+	S3Region               string        `env:"AWS_REGION,default=us-east-1"`
+	S3AccessKeyID          string        `env:"AWS_ACCESS_KEY_ID"`
+	S3SecretAccessKey      string        `env:"AWS_SECRET_ACCESS_KEY"`
+	S3SessionToken         string        `env:"AWS_SESSION_TOKEN"`
 	Version                string        `env:"VERSION"`
 	Build                  string        `env:"BUILD"`
 	Year                   int           `env:"YEAR,default=2024"`
@@ -39,13 +111,49 @@ func NewApp() (*App, error) {
 		return nil, err
 	}
 
-	flag.StringVar(&cfg.Input, "input", "", "Input mode - requires path to input CSV file")
-	flag.StringVar(&cfg.Output, "output", "", "Output mode - requires path to save CSV file")
+	flag.StringVar(&cfg.Input, "input", "", "Input mode - requires path to input CSV file, an s3://bucket/key URL, or - for the standard input")
+	flag.StringVar(&cfg.Output, "output", "", "Output mode - requires a folder or file path to save to, an s3://bucket/prefix URL, or - for the standard output")
+	flag.StringVar(&cfg.Audit, "audit", "", "Audit mode - checks the metadata of the assets against the view, saving a CSV report and summary to a folder or file path, an s3://bucket/prefix URL, or - for the standard output")
+	flag.StringVar(&cfg.Stats, "stats", "", "Stats mode - counts the assets by facet value and totals their file sizes by media type, saving a CSV report and summary to a folder or file path, an s3://bucket/prefix URL, or - for the standard output")
+	flag.StringVar(&cfg.Filename, "filename", "{name}_Report_{timestamp}", "Template of output, audit and stats file names written to a folder, with {name}, {collection_id}, {collection_title} and {timestamp} placeholders")
 	flag.StringVar(&cfg.BaseURL, "iconik-url", "https://app.iconik.io", "the iconik URL")
 	flag.StringVar(&cfg.AppID, "app-id", "", "iconik Application ID")
 	flag.StringVar(&cfg.AuthToken, "auth-token", "", "iconik Authentication token")
-	flag.StringVar(&cfg.CollectionID, "collection-id", "", "iconik Collection ID")
-	flag.StringVar(&cfg.ViewID, "metadata-view-id", "", "iconik Metadata View ID")
+	flag.StringVar(&cfg.CollectionID, "collection-id", "", "iconik Collection ID, or a comma separated list of IDs to output")
+	flag.StringVar(&cfg.ViewID, "metadata-view-id", "", "iconik Metadata View ID, or a comma separated list of IDs")
+	flag.StringVar(&cfg.Format, "format", "", "File format: csv, xlsx, json, ndjson, ale, xmp, ebucore, pbcore or transcripts (input default detects it from the file extension)")
+	flag.StringVar(&cfg.Sheet, "sheet", "", "Name or number of the sheet to read from an input xlsx workbook (default first sheet)")
+	flag.StringVar(&cfg.FPS, "fps", "25", "Frame rate written to the heading of output ALE files, and of segment timecodes")
+	flag.StringVar(&cfg.ALEVideoFormat, "ale-video-format", "", "Video format written to the heading of output ALE files, e.g. 1080, 720, PAL or NTSC (default none)")
+	flag.StringVar(&cfg.ALEAudioFormat, "ale-audio-format", "", "Audio format written to the heading of output ALE files, e.g. 48khz (default none)")
+	flag.BoolVar(&cfg.Segments, "segments", false, "Output a row for each time based segment of each asset, rather than a row for each asset")
+	flag.StringVar(&cfg.SegmentTypes, "segment-type", "", "Comma separated segment types to output, e.g. MARKER,GENERIC (default every type)")
+	flag.BoolVar(&cfg.Timecode, "timecode", false, "Write segment times as HH:MM:SS:FF timecodes at -fps, rather than milliseconds")
+	flag.StringVar(&cfg.XMPMapping, "xmp-mapping", "", "Path to a JSON file mapping view fields to XMP properties")
+	flag.StringVar(&cfg.Profile, "profile", "", "Path to a JSON profile mapping values to EBUCore or PBCore elements (default bundled profile)")
+	flag.BoolVar(&cfg.PerCollection, "per-collection", false, "Write a single EBUCore or PBCore document for the whole collection")
+	flag.BoolVar(&cfg.TranscriptText, "transcript-text", false, "Write a plain text file of each transcript alongside its SRT and WebVTT files")
+	flag.StringVar(&cfg.ObjectType, "object-type", "assets", "Objects to output: assets, collections or both")
+	flag.BoolVar(&cfg.SubcollectionFiles, "subcollection-files", false, "Write a file for each subcollection, in folders mirroring the collection tree")
+	flag.IntVar(&cfg.SplitRows, "split-rows", 0, "Start a new output file, with its own header, every N rows")
+	flag.IntVar(&cfg.SplitMB, "split-mb", 0, "Start a new output file, with its own header, every N megabytes")
+	flag.StringVar(&cfg.Compress, "compress", "", "Compress the output files: gzip or zip")
+	flag.StringVar(&cfg.Resume, "resume", "", "Resume a failed csv or ndjson export - requires path to the partly written file")
+	flag.StringVar(&cfg.Columns, "columns", "id,original_name,size,title", "Comma separated system columns written before the view fields, in order")
+	flag.StringVar(&cfg.Layout, "layout", "wide", "Table layout: wide, with a row per asset, or long, with a row per field value")
+	flag.BoolVar(&cfg.FieldNames, "field-names", false, "Write a second header row of view field names, so input matches columns by name rather than label")
+	flag.BoolVar(&cfg.FileRows, "file-rows", false, "Write a row for each file of an asset, rather than for its first file only")
+	flag.StringVar(&cfg.Timezone, "timezone", "UTC", "IANA timezone exported dates and date times are written in, e.g. Europe/London")
+	flag.StringVar(&cfg.Query, "query", "", "Free text search query selecting the assets to output")
+	flag.Var(&cfg.Filters, "filter", "Filter term selecting the assets to output, as name=value, name=value1,value2 or name=min..max (repeatable)")
+	flag.Var(&cfg.Where, "where", "Condition on a metadata field or system column, compared with its values as written to the export, as name= for empty, name!= for not empty, name=value1,value2 or name!=value1,value2 (repeatable)")
+	flag.StringVar(&cfg.Sort, "sort", "", "Comma separated fields to sort the output by, as name or name:desc (default date_created:desc)")
+	flag.StringVar(&cfg.Since, "since", "", "Only export assets modified after an RFC 3339 time, or \"last\" for the last incremental export")
+	flag.BoolVar(&cfg.Deleted, "deleted", false, "Export assets deleted since the -since time, by their date_deleted, rather than active assets")
+	flag.StringVar(&cfg.Delimiter, "delimiter", "auto", "CSV field delimiter, e.g. \",\", \";\" or \"tab\" (input default detects it)")
+	flag.StringVar(&cfg.Encoding, "encoding", "auto", "Input CSV encoding: auto, utf-8, utf-16le, utf-16be or windows-1252")
+	flag.BoolVar(&cfg.LazyQuotes, "lazy-quotes", false, "Allow unescaped quotes in input CSV fields")
+	flag.BoolVar(&cfg.Excel, "excel", false, "Write an Excel friendly CSV with a byte order mark and CRLF line endings")
 	ver := flag.Bool("version", false, "Print version")
 	flag.Parse()
 
@@ -61,26 +169,77 @@ func NewApp() (*App, error) {
 		os.Exit(1)
 	}
 
-	if cfg.Input != "" && cfg.Output != "" {
-		fmt.Println("both input or output mode selected. Please only select one.")
+	if modes(cfg.Input, cfg.Output, cfg.Audit, cfg.Stats) > 1 {
+		fmt.Println("more than one of input, output, audit or stats mode selected. Please only select one.")
 		return nil, nil
 	}
 
-	if cfg.Input == "" && cfg.Output == "" {
-		fmt.Println("neither input or output mode selected")
+	if cfg.Resume != "" && cfg.Output == "" {
+		cfg.Output = filepath.Dir(cfg.Resume) + string(filepath.Separator)
+	}
+
+	if modes(cfg.Input, cfg.Output, cfg.Audit, cfg.Stats) == 0 {
+		fmt.Println("neither input, output, audit or stats mode selected")
 		return nil, nil
 	}
 
+	// timecodes count a whole number of frames each second, so need at least one.
+	if fps, err := strconv.ParseFloat(cfg.FPS, 64); err != nil || !(fps >= 1) || math.IsInf(fps, 1) {
+		return nil, fmt.Errorf("invalid frame rate %s, expected at least 1", cfg.FPS)
+	}
+
+	if cfg.Output == storage.Stdio || storage.IsS3(cfg.Output) {
+		if cfg.Resume != "" {
+			return nil, errors.New("exports to the standard output or an object store can't be resumed")
+		}
+		if cfg.Since == "last" {
+			return nil, errors.New("-since last needs a local output folder to keep the time of the last export in")
+		}
+	}
+
+	if cfg.Segments && cfg.Resume != "" {
+		return nil, errors.New("segment exports can't be resumed")
+	}
+
+	if cfg.SubcollectionFiles && cfg.Resume != "" {
+		return nil, errors.New("exports split with -subcollection-files can't be resumed")
+	}
+
+	if cfg.SplitRows < 0 || cfg.SplitMB < 0 {
+		return nil, errors.New("-split-rows and -split-mb can't be negative")
+	}
+
+	if cfg.Compress != "" && cfg.Compress != "gzip" && cfg.Compress != "zip" {
+		return nil, fmt.Errorf("unknown compression %s, expected gzip or zip", cfg.Compress)
+	}
+
+	if cfg.Parts() && (cfg.Resume != "" || cfg.SubcollectionFiles) {
+		return nil, errors.New("split or compressed exports can't be resumed or combined with -subcollection-files")
+	}
+
+	if cfg.Layout != "wide" && cfg.Layout != "long" {
+		return nil, fmt.Errorf("unknown layout %s, expected wide or long", cfg.Layout)
+	}
+
+	if cfg.ObjectTypes() == nil {
+		return nil, fmt.Errorf("unknown object type %s, expected assets, collections or both", cfg.ObjectType)
+	}
+
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("unknown timezone %s", cfg.Timezone)
+	}
+
 	if cfg.AppID == "" {
 		return nil, errors.New("no App-Id provided")
 	}
 	if cfg.AuthToken == "" {
 		return nil, errors.New("no Auth-Token provided")
 	}
-	if cfg.CollectionID == "" {
+	if cfg.CollectionID == "" && cfg.Input != "" {
 		return nil, errors.New("no Collection ID provided")
 	}
-	if cfg.ViewID == "" {
+	// stats count the assets whatever their metadata, so need no view.
+	if cfg.ViewID == "" && cfg.Stats == "" {
 		return nil, errors.New("no Metadata View ID provided")
 	}
 
@@ -92,9 +251,144 @@ func NewApp() (*App, error) {
 		cfg.Type = "output"
 	}
 
+	if cfg.Audit != "" {
+		cfg.Type = "audit"
+	}
+
+	if cfg.Stats != "" {
+		cfg.Type = "stats"
+	}
+
 	return &cfg, nil
 }
 
+// modes returns the number of modes selected.
+func modes(targets ...string) int {
+	n := 0
+	for _, t := range targets {
+		if t != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// FileFormat returns the file format selected by the -format flag, or detected from
+// the extension of the input file, or of the output file when output isn't written to a folder.
+// An input folder is treated as a folder of XMP sidecars, and anything else as CSV.
+func (a *App) FileFormat() string {
+	if a.Format != "" {
+		return strings.ToLower(a.Format)
+	}
+
+	name := a.Input
+	if name == "" && !storage.IsFolder(a.Output) {
+		name = a.Output
+	}
+
+	if info, err := os.Stat(a.Input); err == nil && info.IsDir() {
+		return FormatXMP
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xlsx":
+		return FormatXLSX
+	case ".json":
+		return FormatJSON
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".ale":
+		return FormatALE
+	case ".xmp":
+		return FormatXMP
+	}
+
+	return FormatCSV
+}
+
+// ObjectTypes returns the iconik object types selected by the -object-type flag, or nil if
+// it is not a known type.
+func (a *App) ObjectTypes() []string {
+	switch strings.ToLower(a.ObjectType) {
+	case "", "assets":
+		return []string{"assets"}
+	case "collections":
+		return []string{"collections"}
+	case "both":
+		return []string{"assets", "collections"}
+	}
+	return nil
+}
+
+// ViewIDs returns the metadata views selected by the -metadata-view-id flag.
+func (a *App) ViewIDs() []string {
+	var ids []string
+	for _, id := range strings.Split(a.ViewID, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// S3 returns the object store configuration read from the environment.
+func (a *App) S3() storage.S3Config {
+	return storage.S3Config{
+		Endpoint:        a.S3Endpoint,
+		Region:          a.S3Region,
+		AccessKeyID:     a.S3AccessKeyID,
+		SecretAccessKey: a.S3SecretAccessKey,
+		SessionToken:    a.S3SessionToken,
+	}
+}
+
+// Parts reports whether the export is split into parts or compressed, and so listed in a
+// manifest.
+func (a *App) Parts() bool {
+	return a.SplitRows > 0 || a.SplitMB > 0 || a.Compress != ""
+}
+
+// FrameRate returns the frame rate selected by the -fps flag.
+func (a *App) FrameRate() float64 {
+	fps, _ := strconv.ParseFloat(a.FPS, 64)
+	return fps
+}
+
+// CollectionIDs returns the collections selected by the -collection-id flag.
+func (a *App) CollectionIDs() []string {
+	var ids []string
+	for _, id := range strings.Split(a.CollectionID, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Values is a flag which can be given more than once, collecting each value.
+type Values []string
+
+// String returns the values joined by commas.
+func (v *Values) String() string {
+	return strings.Join(*v, ",")
+}
+
+// Set adds a value.
+func (v *Values) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
+
+// CSVDialect returns the CSV dialect selected by the command line flags.
+func (a *App) CSVDialect() csvio.Dialect {
+	return csvio.Dialect{
+		Delimiter:  a.Delimiter,
+		Encoding:   a.Encoding,
+		LazyQuotes: a.LazyQuotes,
+		Excel:      a.Excel,
+	}
+}
+
 // Print prints the version info.
 func (a *App) Print() {
 	fmt.Printf(`
@@ -0,0 +1,179 @@
+/*
+Package ale provides a reader and writer for Avid Log Exchange files, exchanging
+rows in the same shape as the CSV readers and writers.
+*/
+package ale
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// ColumnName is the ALE column holding the clip name.
+	ColumnName = "Name"
+	// ColumnTape is the ALE column holding the tape name.
+	ColumnTape = "Tape"
+	// ColumnSourceFile is the ALE column holding the source file name.
+	ColumnSourceFile = "Source File"
+	// ColumnID is the custom column holding the iconik ID of the asset a clip belongs to.
+	ColumnID = "iconik ID"
+
+	sectionHeading = "Heading"
+	sectionColumn  = "Column"
+	sectionData    = "Data"
+)
+
+// Heading holds the values written to the Heading section of an ALE file. Empty values are
+// left out of the heading.
+type Heading struct {
+	VideoFormat string
+	AudioFormat string
+	FPS         string
+}
+
+// ReadAll reads an ALE file and returns its column headings followed by its data rows.
+// Rows are padded to the number of columns.
+func ReadAll(r io.Reader) ([][]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var columns []string
+	var rows [][]string
+	section := ""
+	first := true
+
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		switch strings.TrimSpace(line) {
+		case sectionHeading, sectionColumn, sectionData:
+			section = strings.TrimSpace(line)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		switch section {
+		case sectionColumn:
+			if columns == nil {
+				columns = trimFields(strings.Split(line, "\t"))
+			}
+		case sectionData:
+			if columns == nil {
+				return nil, errors.New("ALE file has data before its column headings")
+			}
+			row := strings.Split(line, "\t")
+			for len(row) < len(columns) {
+				row = append(row, "")
+			}
+			rows = append(rows, row[:len(columns)])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if columns == nil {
+		return nil, errors.New("ALE file has no Column section")
+	}
+
+	return append([][]string{columns}, rows...), nil
+}
+
+func trimFields(fields []string) []string {
+	for len(fields) > 0 && strings.TrimSpace(fields[len(fields)-1]) == "" {
+		fields = fields[:len(fields)-1]
+	}
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+	}
+	return fields
+}
+
+// Writer writes rows to an ALE file. The first row written is the column headings,
+// and the rows after it are clips.
+type Writer struct {
+	w       *bufio.Writer
+	heading Heading
+	rows    int
+}
+
+// NewWriter returns a new Writer that writes an ALE file to w.
+func NewWriter(w io.Writer, heading Heading) *Writer {
+	return &Writer{
+		w:       bufio.NewWriter(w),
+		heading: heading,
+	}
+}
+
+// WriteAll writes multiple rows and flushes them to the underlying writer.
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// Write writes a single row. The Heading and Column sections are written with the first row,
+// and the Data section is started with the second.
+func (w *Writer) Write(record []string) error {
+	var err error
+	switch w.rows {
+	case 0:
+		err = w.writeHeading()
+	case 1:
+		_, err = fmt.Fprintf(w.w, "\n%s\n", sectionData)
+	}
+	if err != nil {
+		return err
+	}
+	w.rows++
+
+	fields := make([]string, len(record))
+	for i, f := range record {
+		fields[i] = clean(f)
+	}
+
+	_, err = w.w.WriteString(strings.Join(fields, "\t") + "\n")
+	return err
+}
+
+// writeHeading writes the Heading section, and starts the Column section.
+func (w *Writer) writeHeading() error {
+	lines := []string{sectionHeading, "FIELD_DELIM\tTABS"}
+	for _, h := range [][2]string{{"VIDEO_FORMAT", w.heading.VideoFormat}, {"AUDIO_FORMAT", w.heading.AudioFormat}, {"FPS", w.heading.FPS}} {
+		if v := clean(strings.TrimSpace(h[1])); v != "" {
+			lines = append(lines, h[0]+"\t"+v)
+		}
+	}
+
+	_, err := fmt.Fprintf(w.w, "%s\n\n%s\n", strings.Join(lines, "\n"), sectionColumn)
+	return err
+}
+
+// Close writes the Data section of a file without clips, and flushes the underlying writer.
+func (w *Writer) Close() error {
+	if w.rows == 1 {
+		if _, err := fmt.Fprintf(w.w, "\n%s\n", sectionData); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// clean replaces the characters ALE can't hold in a field with spaces.
+func clean(s string) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
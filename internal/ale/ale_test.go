@@ -0,0 +1,161 @@
+package ale
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	const heading = "Heading\nFIELD_DELIM\tTABS\nVIDEO_FORMAT\t1080\nFPS\t25\n\n"
+
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+		err   string
+	}{
+		{
+			name:  "clips",
+			input: heading + "Column\nName\tTape\ticonik ID\n\nData\nClip 1\tA001\t1\nClip 2\tA002\t2\n",
+			want:  [][]string{{"Name", "Tape", "iconik ID"}, {"Clip 1", "A001", "1"}, {"Clip 2", "A002", "2"}},
+		},
+		{
+			name:  "crlf and bom",
+			input: "\ufeff" + strings.ReplaceAll(heading+"Column\nName\tTape\n\nData\nClip 1\tA001\n", "\n", "\r\n"),
+			want:  [][]string{{"Name", "Tape"}, {"Clip 1", "A001"}},
+		},
+		{
+			// the column headings are trimmed, and a trailing tab adds no column.
+			name:  "trailing tab",
+			input: heading + "Column\n Name \tTape\t\n\nData\nClip 1\tA001\t\n",
+			want:  [][]string{{"Name", "Tape"}, {"Clip 1", "A001"}},
+		},
+		{
+			name:  "short and long rows",
+			input: heading + "Column\nName\tTape\tScene\n\nData\nClip 1\nClip 2\tA002\t3\textra\n",
+			want:  [][]string{{"Name", "Tape", "Scene"}, {"Clip 1", "", ""}, {"Clip 2", "A002", "3"}},
+		},
+		{
+			name:  "empty values kept",
+			input: heading + "Column\nName\tTape\tScene\n\nData\n\tA001\t\n",
+			want:  [][]string{{"Name", "Tape", "Scene"}, {"", "A001", ""}},
+		},
+		{
+			name:  "no clips",
+			input: heading + "Column\nName\tTape\n\nData\n",
+			want:  [][]string{{"Name", "Tape"}},
+		},
+		{
+			name:  "no heading",
+			input: "Column\nName\n\nData\nClip 1\n",
+			want:  [][]string{{"Name"}, {"Clip 1"}},
+		},
+		{
+			name:  "no column section",
+			input: heading,
+			err:   "ALE file has no Column section",
+		},
+		{
+			name:  "data before columns",
+			input: heading + "Data\nClip 1\n\nColumn\nName\n",
+			err:   "ALE file has data before its column headings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAll(strings.NewReader(tt.input))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ReadAll() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	heading := Heading{VideoFormat: "1080", AudioFormat: "48khz", FPS: "25"}
+	const head = "Heading\nFIELD_DELIM\tTABS\nVIDEO_FORMAT\t1080\nAUDIO_FORMAT\t48khz\nFPS\t25\n\nColumn\n"
+
+	tests := []struct {
+		name    string
+		records [][]string
+		want    string
+	}{
+		{
+			name:    "clips",
+			records: [][]string{{"Name", "iconik ID"}, {"Clip 1", "1"}, {"Clip 2", "2"}},
+			want:    head + "Name\ticonik ID\n\nData\nClip 1\t1\nClip 2\t2\n",
+		},
+		{
+			name:    "no clips",
+			records: [][]string{{"Name", "iconik ID"}},
+			want:    head + "Name\ticonik ID\n\nData\n",
+		},
+		{
+			name:    "tabs and line breaks",
+			records: [][]string{{"Name", "Comments"}, {"Clip\t1", "one\r\ntwo\nthree\rfour"}},
+			want:    head + "Name\tComments\n\nData\nClip 1\tone two three four\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := NewWriter(&b, heading)
+			if err := w.WriteAll(tt.records); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Writer wrote %q, want %q", b.String(), tt.want)
+			}
+
+			got, err := ReadAll(strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.records) {
+				t.Errorf("ReadAll() read %d rows, want %d", len(got), len(tt.records))
+			}
+		})
+	}
+}
+
+func TestWriterHeading(t *testing.T) {
+	tests := []struct {
+		name    string
+		heading Heading
+		want    string
+	}{
+		{"every value", Heading{VideoFormat: "1080", AudioFormat: "48khz", FPS: "25"}, "Heading\nFIELD_DELIM\tTABS\nVIDEO_FORMAT\t1080\nAUDIO_FORMAT\t48khz\nFPS\t25\n\n"},
+		// formats which aren't known are left out, rather than guessed.
+		{"frame rate only", Heading{FPS: "23.976"}, "Heading\nFIELD_DELIM\tTABS\nFPS\t23.976\n\n"},
+		{"none", Heading{VideoFormat: " "}, "Heading\nFIELD_DELIM\tTABS\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := NewWriter(&b, tt.heading)
+			if err := w.WriteAll([][]string{{"Name"}}); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want + "Column\nName\n"
+			if b.String() != want {
+				t.Errorf("Writer wrote %q, want %q", b.String(), want)
+			}
+		})
+	}
+}
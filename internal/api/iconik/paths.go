@@ -9,6 +9,8 @@ const (
 	MetadataViewPath = "/API/metadata/v1/views/"
 	// MetadataAssetsPath is the path used for hitting the iconik metadata assets endpoints.
 	MetadataAssetsPath = "/API/metadata/v1/assets/"
+	// MetadataCollectionsPath is the path used for hitting the iconik metadata collections endpoints.
+	MetadataCollectionsPath = "/API/metadata/v1/collections/"
 	// SearchPath is the path used for hitting the iconik search endpoints.
 	SearchPath = "/API/search/v1/search/"
 )
@@ -0,0 +1,479 @@
+package iconik
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/avast/retry-go"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/segments"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+// GetSegments makes a request to the GET iconik asset segments endpoint.
+func (a *API) GetSegments(ctx context.Context, path, assetID string, queryParams map[string]string) (segments.SegmentsDTO, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, a.cfg.OperationTimeout)
+	defer cancel()
+
+	body, statusCode, err := a.req.Do(
+		ctxTimeout,
+		http.MethodGet,
+		fmt.Sprintf("%v%v%v/segments/", a.url, path, assetID),
+		a.headers,
+		queryParams,
+		nil,
+	)
+
+	opDelay := a.cfg.OperationRetryDelay
+
+	switch {
+	case statusCode == nil:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("status code is nil")
+		return segments.SegmentsDTO{}, err
+	case *statusCode == http.StatusTooManyRequests,
+		*statusCode == http.StatusInternalServerError,
+		*statusCode == http.StatusServiceUnavailable,
+		*statusCode == http.StatusGatewayTimeout:
+		f := func() error {
+			body, statusCode, err = a.req.Do(
+				ctxTimeout,
+				http.MethodGet,
+				fmt.Sprintf("%v%v%v/segments/", a.url, path, assetID),
+				a.headers,
+				queryParams,
+				nil,
+			)
+			return err
+		}
+		onRetry := func(n uint, err error) {
+			zerolog.Ctx(ctxTimeout).
+				Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("retrying to get segments from iconik")
+		}
+		if *statusCode != http.StatusTooManyRequests {
+			opDelay = 0
+		}
+		_ = retry.Do(
+			f,
+			retry.Attempts(a.cfg.OperationRetryAttempts),
+			retry.Delay(opDelay),
+			retry.OnRetry(onRetry),
+		)
+	case *statusCode == http.StatusForbidden:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("forbidden when getting segments")
+		return segments.SegmentsDTO{}, domain.ErrForbidden
+	case *statusCode == http.StatusUnauthorized:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("unauthorized when getting segments")
+		return segments.SegmentsDTO{},
+			fmt.Errorf("you do not have the correct permissions to get segments for asset %s", assetID)
+	case *statusCode != http.StatusOK:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			RawJSON("response", body).
+			Int("status code", *statusCode).
+			Msg("status code unexpected")
+		return segments.SegmentsDTO{}, domain.ErrInternalError
+	}
+
+	if err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error getting segments")
+		return segments.SegmentsDTO{}, err
+	}
+
+	var res segments.Segments
+	if err = json.Unmarshal(body, &res); err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error unmarshalling body")
+		return segments.SegmentsDTO{}, err
+	}
+
+	return res.ToSegmentsDTO(), nil
+}
+
+// PostSegment makes a request to the POST iconik asset segments endpoint.
+func (a *API) PostSegment(ctx context.Context, path, assetID string, payload []byte) (segments.SegmentDTO, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, a.cfg.OperationTimeout)
+	defer cancel()
+
+	body, statusCode, err := a.req.Do(
+		ctxTimeout,
+		http.MethodPost,
+		fmt.Sprintf("%v%v%v/segments/", a.url, path, assetID),
+		a.headers,
+		nil,
+		payload,
+	)
+
+	opDelay := a.cfg.OperationRetryDelay
+
+	switch {
+	case statusCode == nil:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("status code is nil")
+		return segments.SegmentDTO{}, err
+	case *statusCode == http.StatusTooManyRequests,
+		*statusCode == http.StatusInternalServerError,
+		*statusCode == http.StatusServiceUnavailable,
+		*statusCode == http.StatusGatewayTimeout:
+		f := func() error {
+			body, statusCode, err = a.req.Do(
+				ctxTimeout,
+				http.MethodPost,
+				fmt.Sprintf("%v%v%v/segments/", a.url, path, assetID),
+				a.headers,
+				nil,
+				payload,
+			)
+			return err
+		}
+		onRetry := func(n uint, err error) {
+			zerolog.Ctx(ctxTimeout).
+				Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("retrying to create segment in iconik")
+		}
+		if *statusCode != http.StatusTooManyRequests {
+			opDelay = 0
+		}
+		_ = retry.Do(
+			f,
+			retry.Attempts(a.cfg.OperationRetryAttempts),
+			retry.Delay(opDelay),
+			retry.OnRetry(onRetry),
+		)
+	case *statusCode == http.StatusForbidden:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("forbidden when creating segment")
+		return segments.SegmentDTO{}, domain.ErrForbidden
+	case *statusCode == http.StatusUnauthorized:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("unauthorized when creating segment")
+		return segments.SegmentDTO{},
+			fmt.Errorf("you do not have the correct permissions to create segments for asset %s", assetID)
+	case *statusCode != http.StatusCreated && *statusCode != http.StatusOK:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			RawJSON("response", body).
+			Int("status code", *statusCode).
+			Msg("status code unexpected")
+		return segments.SegmentDTO{}, domain.ErrInternalError
+	}
+
+	if err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error creating segment")
+		return segments.SegmentDTO{}, err
+	}
+
+	var res segments.Segment
+	if err = json.Unmarshal(body, &res); err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error unmarshalling body")
+		return segments.SegmentDTO{}, err
+	}
+
+	return res.ToSegmentDTO(), nil
+}
+
+// PatchSegment makes a request to the PATCH iconik asset segment endpoint.
+func (a *API) PatchSegment(ctx context.Context, path, assetID, segmentID string, payload []byte) (segments.SegmentDTO, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, a.cfg.OperationTimeout)
+	defer cancel()
+
+	body, statusCode, err := a.req.Do(
+		ctxTimeout,
+		http.MethodPatch,
+		fmt.Sprintf("%v%v%v/segments/%v/", a.url, path, assetID, segmentID),
+		a.headers,
+		nil,
+		payload,
+	)
+
+	opDelay := a.cfg.OperationRetryDelay
+
+	switch {
+	case statusCode == nil:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("status code is nil")
+		return segments.SegmentDTO{}, err
+	case *statusCode == http.StatusTooManyRequests,
+		*statusCode == http.StatusInternalServerError,
+		*statusCode == http.StatusServiceUnavailable,
+		*statusCode == http.StatusGatewayTimeout:
+		f := func() error {
+			body, statusCode, err = a.req.Do(
+				ctxTimeout,
+				http.MethodPatch,
+				fmt.Sprintf("%v%v%v/segments/%v/", a.url, path, assetID, segmentID),
+				a.headers,
+				nil,
+				payload,
+			)
+			return err
+		}
+		onRetry := func(n uint, err error) {
+			zerolog.Ctx(ctxTimeout).
+				Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("retrying to update segment in iconik")
+		}
+		if *statusCode != http.StatusTooManyRequests {
+			opDelay = 0
+		}
+		_ = retry.Do(
+			f,
+			retry.Attempts(a.cfg.OperationRetryAttempts),
+			retry.Delay(opDelay),
+			retry.OnRetry(onRetry),
+		)
+	case *statusCode == http.StatusForbidden:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("forbidden when updating segment")
+		return segments.SegmentDTO{}, domain.ErrForbidden
+	case *statusCode == http.StatusUnauthorized:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("unauthorized when updating segment")
+		return segments.SegmentDTO{},
+			fmt.Errorf("you do not have the correct permissions to update segments for asset %s", assetID)
+	case *statusCode != http.StatusOK:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			RawJSON("response", body).
+			Int("status code", *statusCode).
+			Msg("status code unexpected")
+		return segments.SegmentDTO{}, domain.ErrInternalError
+	}
+
+	if err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error updating segment")
+		return segments.SegmentDTO{}, err
+	}
+
+	var res segments.Segment
+	if err = json.Unmarshal(body, &res); err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error unmarshalling body")
+		return segments.SegmentDTO{}, err
+	}
+
+	return res.ToSegmentDTO(), nil
+}
+
+// DeleteSegment makes a request to the DELETE iconik asset segment endpoint.
+func (a *API) DeleteSegment(ctx context.Context, path, assetID, segmentID string) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, a.cfg.OperationTimeout)
+	defer cancel()
+
+	body, statusCode, err := a.req.Do(
+		ctxTimeout,
+		http.MethodDelete,
+		fmt.Sprintf("%v%v%v/segments/%v/", a.url, path, assetID, segmentID),
+		a.headers,
+		nil,
+		nil,
+	)
+
+	opDelay := a.cfg.OperationRetryDelay
+
+	switch {
+	case statusCode == nil:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("status code is nil")
+		return err
+	case *statusCode == http.StatusTooManyRequests,
+		*statusCode == http.StatusInternalServerError,
+		*statusCode == http.StatusServiceUnavailable,
+		*statusCode == http.StatusGatewayTimeout:
+		f := func() error {
+			body, statusCode, err = a.req.Do(
+				ctxTimeout,
+				http.MethodDelete,
+				fmt.Sprintf("%v%v%v/segments/%v/", a.url, path, assetID, segmentID),
+				a.headers,
+				nil,
+				nil,
+			)
+			return err
+		}
+		onRetry := func(n uint, err error) {
+			zerolog.Ctx(ctxTimeout).
+				Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("retrying to delete segment in iconik")
+		}
+		if *statusCode != http.StatusTooManyRequests {
+			opDelay = 0
+		}
+		_ = retry.Do(
+			f,
+			retry.Attempts(a.cfg.OperationRetryAttempts),
+			retry.Delay(opDelay),
+			retry.OnRetry(onRetry),
+		)
+	case *statusCode == http.StatusForbidden:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("forbidden when deleting segment")
+		return domain.ErrForbidden
+	case *statusCode == http.StatusUnauthorized:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("unauthorized when deleting segment")
+		return fmt.Errorf("you do not have the correct permissions to delete segments for asset %s", assetID)
+	case *statusCode != http.StatusNoContent && *statusCode != http.StatusOK:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			RawJSON("response", body).
+			Int("status code", *statusCode).
+			Msg("status code unexpected")
+		return domain.ErrInternalError
+	}
+
+	if err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error deleting segment")
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMetadataInSegment makes a request to the PUT iconik segment metadata endpoint.
+func (a *API) UpdateMetadataInSegment(ctx context.Context, path, viewID, assetID, segmentID string, payload []byte) (metadata.DTO, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, a.cfg.OperationTimeout)
+	defer cancel()
+
+	body, statusCode, err := a.req.Do(
+		ctxTimeout,
+		http.MethodPut,
+		fmt.Sprintf("%v%v%v/segments/%v/views/%v/", a.url, path, assetID, segmentID, viewID),
+		a.headers,
+		nil,
+		payload,
+	)
+
+	opDelay := a.cfg.OperationRetryDelay
+
+	switch {
+	case statusCode == nil:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("status code is nil")
+		return metadata.DTO{}, err
+	case *statusCode == http.StatusTooManyRequests,
+		*statusCode == http.StatusInternalServerError,
+		*statusCode == http.StatusServiceUnavailable,
+		*statusCode == http.StatusGatewayTimeout:
+		f := func() error {
+			body, statusCode, err = a.req.Do(
+				ctxTimeout,
+				http.MethodPut,
+				fmt.Sprintf("%v%v%v/segments/%v/views/%v/", a.url, path, assetID, segmentID, viewID),
+				a.headers,
+				nil,
+				payload,
+			)
+			return err
+		}
+		onRetry := func(n uint, err error) {
+			zerolog.Ctx(ctxTimeout).
+				Debug().
+				Err(err).
+				Uint("attempt", n+1).
+				Msg("retrying to update segment metadata in iconik")
+		}
+		if *statusCode != http.StatusTooManyRequests {
+			opDelay = 0
+		}
+		_ = retry.Do(
+			f,
+			retry.Attempts(a.cfg.OperationRetryAttempts),
+			retry.Delay(opDelay),
+			retry.OnRetry(onRetry),
+		)
+	case *statusCode == http.StatusForbidden:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("forbidden when updating segment metadata")
+		return metadata.DTO{}, domain.ErrForbidden
+	case *statusCode == http.StatusUnauthorized:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Int("status code", *statusCode).
+			RawJSON("response", body).
+			Msg("unauthorized when updating segment metadata")
+		return metadata.DTO{},
+			fmt.Errorf("you do not have the correct permissions to update segment metadata for asset %s", assetID)
+	case *statusCode != http.StatusOK:
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			RawJSON("response", body).
+			Int("status code", *statusCode).
+			Msg("status code unexpected")
+		return metadata.DTO{}, domain.ErrInternalError
+	}
+
+	if err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error updating segment metadata")
+		return metadata.DTO{}, err
+	}
+
+	var res metadata.Metadata
+	if err = json.Unmarshal(body, &res); err != nil {
+		zerolog.Ctx(ctxTimeout).Error().
+			Err(err).
+			Msg("error unmarshalling body")
+		return metadata.DTO{}, err
+	}
+
+	return res.ToDTO(), nil
+}
@@ -89,6 +89,7 @@ type Collection struct {
 	IsRoot            bool      `json:"is_root"`
 	KeyframeAssetIds  []string  `json:"keyframe_asset_ids"`
 	ObjectType        string    `json:"object_type"`
+	ParentID          string    `json:"parent_id"`
 	Status            string    `json:"status"`
 	Title             string    `json:"title"`
 }
@@ -103,6 +104,7 @@ func (co *Collection) ToCollectionDTO() CollectionDTO {
 		IsRoot:            co.IsRoot,
 		KeyframeAssetIds:  co.KeyframeAssetIds,
 		ObjectType:        co.ObjectType,
+		ParentID:          co.ParentID,
 		Status:            co.Status,
 		Title:             co.Title,
 	}
@@ -38,6 +38,7 @@ type CollectionDTO struct {
 	IsRoot            bool
 	KeyframeAssetIds  []string
 	ObjectType        string
+	ParentID          string
 	Status            string
 	Title             string
 }
@@ -0,0 +1,19 @@
+package segments
+
+type SegmentsDTO struct {
+	Objects []SegmentDTO
+	Page    int
+	Pages   int
+	Errors  interface{}
+}
+
+type SegmentDTO struct {
+	ID                    string
+	AssetID               string
+	SegmentType           string
+	SegmentText           string
+	TimeStartMilliseconds int64
+	TimeEndMilliseconds   int64
+	Metadata              map[string][]interface{}
+	TranscriptionText     string
+}
@@ -0,0 +1,70 @@
+package segments
+
+// Segments is the top level data structure that receives the unmarshalled payload
+// response from GET asset segments (/API/assets/v1/assets/{asset-id}/segments/).
+type Segments struct {
+	Objects []Segment   `json:"objects"`
+	Page    int         `json:"page"`
+	Pages   int         `json:"pages"`
+	Errors  interface{} `json:"errors"`
+}
+
+// Segment is a time based segment of an asset, such as a marker or a shot.
+type Segment struct {
+	ID                    string                   `json:"id,omitempty"`
+	AssetID               string                   `json:"asset_id,omitempty"`
+	SegmentType           string                   `json:"segment_type"`
+	SegmentText           string                   `json:"segment_text,omitempty"`
+	TimeStartMilliseconds int64                    `json:"time_start_milliseconds"`
+	TimeEndMilliseconds   int64                    `json:"time_end_milliseconds"`
+	Metadata              map[string][]interface{} `json:"metadata,omitempty"`
+	Transcription         *Transcription           `json:"transcription,omitempty"`
+}
+
+// Transcription is the line of speech held by a TRANSCRIPTION segment.
+type Transcription struct {
+	Text string `json:"text"`
+}
+
+// ToSegmentsDTO is a method that converts a Segments to a SegmentsDTO.
+func (s *Segments) ToSegmentsDTO() SegmentsDTO {
+	segmentDTOs := make([]SegmentDTO, len(s.Objects))
+	for i, segment := range s.Objects {
+		segmentDTOs[i] = segment.ToSegmentDTO()
+	}
+
+	return SegmentsDTO{
+		Objects: segmentDTOs,
+		Page:    s.Page,
+		Pages:   s.Pages,
+		Errors:  s.Errors,
+	}
+}
+
+// ToSegmentDTO is a method that converts a Segment to a SegmentDTO.
+func (s *Segment) ToSegmentDTO() SegmentDTO {
+	dto := SegmentDTO{
+		ID:                    s.ID,
+		AssetID:               s.AssetID,
+		SegmentType:           s.SegmentType,
+		SegmentText:           s.SegmentText,
+		TimeStartMilliseconds: s.TimeStartMilliseconds,
+		TimeEndMilliseconds:   s.TimeEndMilliseconds,
+		Metadata:              s.Metadata,
+	}
+	if s.Transcription != nil {
+		dto.TranscriptionText = s.Transcription.Text
+	}
+
+	return dto
+}
+
+// ToSegment is a method that converts a SegmentDTO to the Segment payload of a request.
+func (s *SegmentDTO) ToSegment() Segment {
+	return Segment{
+		SegmentType:           s.SegmentType,
+		SegmentText:           s.SegmentText,
+		TimeStartMilliseconds: s.TimeStartMilliseconds,
+		TimeEndMilliseconds:   s.TimeEndMilliseconds,
+	}
+}
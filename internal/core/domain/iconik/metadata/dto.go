@@ -1,6 +1,7 @@
 package metadata
 
 type DTO struct {
+	ID          string
 	Name        string
 	Description string
 	ViewFields  []ViewFieldDTO
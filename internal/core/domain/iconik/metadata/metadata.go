@@ -1,5 +1,7 @@
 package metadata
 
+import "strings"
+
 // ====================================================
 // iconik Objects Response Structure "GET /API/metadata/v1/views/"
 
@@ -68,11 +70,68 @@ func (o *Option) ToOptionDTO() OptionDTO {
 }
 
 type Values struct {
-	MetadataValues map[string]struct {
-		FieldValues []FieldValue `json:"field_values"`
-	} `json:"metadata_values"`
+	MetadataValues map[string]FieldValues `json:"metadata_values"`
+}
+
+type FieldValues struct {
+	FieldValues []FieldValue `json:"field_values"`
 }
 
 type FieldValue struct {
-	Value string `json:"value"`
+	Value interface{} `json:"value"`
+}
+
+// NewValues returns a Values with an empty, non-nil map of metadata values.
+func NewValues() Values {
+	return Values{
+		MetadataValues: make(map[string]FieldValues),
+	}
+}
+
+// Set sets the values of the named field, replacing any values it already had.
+func (v *Values) Set(name string, values ...interface{}) {
+	fieldValues := make([]FieldValue, len(values))
+	for i, val := range values {
+		fieldValues[i] = FieldValue{Value: val}
+	}
+	v.MetadataValues[name] = FieldValues{FieldValues: fieldValues}
+}
+
+// Merge merges views into a single view holding the fields of each in turn. Fields are shared
+// between views, so a field in several views is kept once, where it first appears. The merged
+// view is named after the views, and has the IDs of the views separated by commas.
+func Merge(views ...DTO) DTO {
+	if len(views) == 1 {
+		return views[0]
+	}
+
+	var merged DTO
+	var ids, names []string
+	seen := make(map[string]bool)
+	for _, view := range views {
+		ids = append(ids, view.ID)
+		names = append(names, view.Name)
+		for _, field := range view.ViewFields {
+			if field.Name != "__separator__" && seen[field.Name] {
+				continue
+			}
+			seen[field.Name] = true
+			merged.ViewFields = append(merged.ViewFields, field)
+		}
+	}
+	merged.ID = strings.Join(ids, ",")
+	merged.Name = strings.Join(names, ", ")
+
+	return merged
+}
+
+// Only returns the values of the fields of view.
+func (v Values) Only(view DTO) Values {
+	only := NewValues()
+	for _, field := range view.ViewFields {
+		if values, ok := v.MetadataValues[field.Name]; ok {
+			only.MetadataValues[field.Name] = values
+		}
+	}
+	return only
 }
@@ -12,9 +12,20 @@ type ResultsDTO struct {
 	PerPage  int
 	PrevUrl  string
 	Total    int
+	Facets   map[string]FacetDTO
 	Errors   interface{}
 }
 
+type FacetDTO struct {
+	Buckets []BucketDTO
+	Other   int
+}
+
+type BucketDTO struct {
+	Value string
+	Count int
+}
+
 type ObjectDTO struct {
 	Sort                  []interface{}
 	AnalyzeStatus         string
@@ -26,6 +37,7 @@ type ObjectDTO struct {
 	DateCreated           time.Time
 	DateModified          time.Time
 	Duration              string
+	DurationMilliseconds  int
 	ExternalLink          interface{}
 	Files                 []FileDTO
 	Format                string
@@ -48,10 +60,16 @@ type ObjectDTO struct {
 }
 
 type FileDTO struct {
-	Id           string
-	Name         string
-	OriginalName string
-	Size         int
+	DirectoryPath string
+	FileSetId     string
+	FormatId      string
+	Id            string
+	Name          string
+	OriginalName  string
+	Size          int
+	Status        string
+	StorageId     string
+	StorageMethod string
 }
 
 type VersionDTO struct {
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -23,7 +24,14 @@ type Sort struct {
 
 type Term struct {
 	Name    string   `json:"name"`
-	ValueIn []string `json:"value_in"`
+	ValueIn []string `json:"value_in,omitempty"`
+	Range   *Range   `json:"range,omitempty"`
+}
+
+// Range limits a term to values between Min and Max. Either may be left empty.
+type Range struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
 }
 
 type FacetsFilter struct {
@@ -37,16 +45,31 @@ type Filter struct {
 }
 
 type Results struct {
-	FirstUrl string      `json:"first_url"`
-	LastUrl  string      `json:"last_url"`
-	NextUrl  string      `json:"next_url"`
-	Objects  []Object    `json:"objects"`
-	Page     int         `json:"page"`
-	Pages    int         `json:"pages"`
-	PerPage  int         `json:"per_page"`
-	PrevUrl  string      `json:"prev_url"`
-	Total    int         `json:"total"`
-	Errors   interface{} `json:"errors"`
+	FirstUrl string           `json:"first_url"`
+	LastUrl  string           `json:"last_url"`
+	NextUrl  string           `json:"next_url"`
+	Objects  []Object         `json:"objects"`
+	Page     int              `json:"page"`
+	Pages    int              `json:"pages"`
+	PerPage  int              `json:"per_page"`
+	PrevUrl  string           `json:"prev_url"`
+	Total    int              `json:"total"`
+	Facets   map[string]Facet `json:"facets"`
+	Errors   interface{}      `json:"errors"`
+}
+
+// Facet holds the counts of the search results for each value of a facet field.
+type Facet struct {
+	Buckets []Bucket `json:"buckets"`
+	// SumOtherDocCount counts the results with values left out of the buckets.
+	SumOtherDocCount int `json:"sum_other_doc_count"`
+}
+
+// Bucket is the count of the search results holding a single facet value.
+type Bucket struct {
+	Key         interface{} `json:"key"`
+	KeyAsString string      `json:"key_as_string"`
+	DocCount    int         `json:"doc_count"`
 }
 
 type Object struct {
@@ -60,6 +83,7 @@ type Object struct {
 	DateCreated           time.Time                `json:"date_created"`
 	DateModified          time.Time                `json:"date_modified"`
 	Duration              string                   `json:"duration"`
+	DurationMilliseconds  int                      `json:"duration_milliseconds"`
 	ExternalLink          interface{}              `json:"external_link"`
 	Files                 []File                   `json:"files"`
 	Format                string                   `json:"format"`
@@ -83,10 +107,16 @@ type Object struct {
 
 // File acts as a non nested struct to the Files type in Object.
 type File struct {
-	Id           string `json:"id"`
-	Name         string `json:"name"`
-	OriginalName string `json:"original_name"`
-	Size         int    `json:"size"`
+	DirectoryPath string `json:"directory_path"`
+	FileSetId     string `json:"file_set_id"`
+	FormatId      string `json:"format_id"`
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	OriginalName  string `json:"original_name"`
+	Size          int    `json:"size"`
+	Status        string `json:"status"`
+	StorageId     string `json:"storage_id"`
+	StorageMethod string `json:"storage_method"`
 }
 
 type Version struct {
@@ -107,6 +137,11 @@ func (r *Results) ToResultsDTO() ResultsDTO {
 		objectDTOs[i] = object.ToObjectDTO()
 	}
 
+	facetDTOs := make(map[string]FacetDTO, len(r.Facets))
+	for name, facet := range r.Facets {
+		facetDTOs[name] = facet.ToFacetDTO()
+	}
+
 	return ResultsDTO{
 		FirstUrl: r.FirstUrl,
 		LastUrl:  r.LastUrl,
@@ -117,10 +152,28 @@ func (r *Results) ToResultsDTO() ResultsDTO {
 		PerPage:  r.PerPage,
 		PrevUrl:  r.PrevUrl,
 		Total:    r.Total,
+		Facets:   facetDTOs,
 		Errors:   r.Errors,
 	}
 }
 
+// ToFacetDTO is a method that converts a Facet to a FacetDTO.
+func (f *Facet) ToFacetDTO() FacetDTO {
+	buckets := make([]BucketDTO, len(f.Buckets))
+	for i, b := range f.Buckets {
+		value := b.KeyAsString
+		if value == "" {
+			value = fmt.Sprint(b.Key)
+		}
+		buckets[i] = BucketDTO{Value: value, Count: b.DocCount}
+	}
+
+	return FacetDTO{
+		Buckets: buckets,
+		Other:   f.SumOtherDocCount,
+	}
+}
+
 // ToObjectDTO is a method that converts an Object to an ObjectDTO.
 func (o *Object) ToObjectDTO() ObjectDTO {
 	fileDTOs := make([]FileDTO, len(o.Files))
@@ -144,6 +197,7 @@ func (o *Object) ToObjectDTO() ObjectDTO {
 		DateCreated:           o.DateCreated,
 		DateModified:          o.DateModified,
 		Duration:              o.Duration,
+		DurationMilliseconds:  o.DurationMilliseconds,
 		ExternalLink:          o.ExternalLink,
 		Files:                 fileDTOs,
 		Format:                o.Format,
@@ -169,10 +223,16 @@ func (o *Object) ToObjectDTO() ObjectDTO {
 // ToFileDTO is a method that converts a File to a FileDTO.
 func (f *File) ToFileDTO() FileDTO {
 	return FileDTO{
-		Id:           f.Id,
-		Name:         f.Name,
-		OriginalName: f.OriginalName,
-		Size:         f.Size,
+		DirectoryPath: f.DirectoryPath,
+		FileSetId:     f.FileSetId,
+		FormatId:      f.FormatId,
+		Id:            f.Id,
+		Name:          f.Name,
+		OriginalName:  f.OriginalName,
+		Size:          f.Size,
+		Status:        f.Status,
+		StorageId:     f.StorageId,
+		StorageMethod: f.StorageMethod,
 	}
 }
 
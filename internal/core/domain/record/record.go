@@ -0,0 +1,123 @@
+package record
+
+import (
+	"strings"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+)
+
+// Record is a single asset or collection as it is read from or written to a file, independent
+// of the file format. Metadata values are keyed by field name, in the shape the iconik
+// metadata endpoint accepts.
+type Record struct {
+	ID           string `json:"id"`
+	OriginalName string `json:"original_name"`
+	Size         *int   `json:"size"`
+	Title        string `json:"title"`
+	// ObjectType is either ObjectTypeAssets or ObjectTypeCollections. Records without one
+	// are assets.
+	ObjectType string `json:"object_type,omitempty"`
+	metadatadomain.Values
+}
+
+const (
+	// ObjectTypeAssets is the object type of asset records.
+	ObjectTypeAssets = "assets"
+	// ObjectTypeCollections is the object type of collection records.
+	ObjectTypeCollections = "collections"
+)
+
+// New returns a new Record with an empty set of metadata values.
+func New(id, originalName, title string) Record {
+	return Record{
+		ID:           id,
+		OriginalName: originalName,
+		Title:        title,
+		Values:       metadatadomain.NewValues(),
+	}
+}
+
+// SystemColumns are the names of the asset and file columns a tabular file can hold before the
+// view fields, defaults first. Only id, original_name, size, title and object_type are read back
+// into a Record; the others describe the asset and are ignored on input.
+var SystemColumns = []string{
+	"id", "original_name", "size", "title", "date_created", "date_modified", "media_type",
+	"format", "duration", "archive_status", "is_online", "versions_number", "created_by_user",
+	"file_id", "file_name", "directory_path", "storage_id", "storage_method", "format_id",
+	"file_set_id", "file_status", "files", "collection_path", "object_type",
+}
+
+// IsSystemColumn reports whether name is one of the SystemColumns.
+func IsSystemColumn(name string) bool {
+	for _, c := range SystemColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentColumns are the columns of a segment table which describe the segment itself, written
+// before the view fields of a segment export.
+var SegmentColumns = []string{"asset_id", "segment_id", "segment_type", "time_start", "time_end", "text"}
+
+// IsSegmentColumn reports whether name is one of the SegmentColumns.
+func IsSegmentColumn(name string) bool {
+	for _, c := range SegmentColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueSeparator separates the values of a field written to a single cell of a table.
+const ValueSeparator = ','
+
+// JoinValues joins the values of a field into a single cell, escaping the separators and
+// backslashes within each value with a backslash, so values such as "Smith, John" or nested
+// objects written as JSON are read back whole by SplitValues.
+func JoinValues(values []string) string {
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteRune(ValueSeparator)
+		}
+		for _, c := range v {
+			if c == ValueSeparator || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// SplitValues splits a cell written by JoinValues into the values of a field. A backslash
+// which doesn't escape a separator or another backslash is kept as it is.
+func SplitValues(cell string) []string {
+	var values []string
+	var b strings.Builder
+	escaped := false
+	for _, c := range cell {
+		switch {
+		case escaped:
+			if c != ValueSeparator && c != '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ValueSeparator:
+			values = append(values, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	if escaped {
+		b.WriteByte('\\')
+	}
+	return append(values, b.String())
+}
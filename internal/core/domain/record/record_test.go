@@ -0,0 +1,52 @@
+package record
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestJoinValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		cell   string
+	}{
+		{"single value", []string{"news"}, "news"},
+		{"several values", []string{"news", "sport"}, "news,sport"},
+		{"comma in value", []string{"Smith, John", "Jones"}, `Smith\, John,Jones`},
+		{"nested object", []string{`{"a":1,"b":2}`}, `{"a":1\,"b":2}`},
+		{"backslash in value", []string{`C:\clips`}, `C:\\clips`},
+		{"empty value", []string{"", "a"}, ",a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell := JoinValues(tt.values)
+			if cell != tt.cell {
+				t.Errorf("JoinValues(%q) = %q, want %q", tt.values, cell, tt.cell)
+			}
+			if got := SplitValues(cell); !slices.Equal(got, tt.values) {
+				t.Errorf("SplitValues(%q) = %q, want %q", cell, got, tt.values)
+			}
+		})
+	}
+}
+
+func TestSplitValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		cell   string
+		values []string
+	}{
+		{"unescaped commas", "a,b,c", []string{"a", "b", "c"}},
+		{"lone backslash kept", `C:\clips`, []string{`C:\clips`}},
+		{"trailing backslash kept", `a\`, []string{`a\`}},
+		{"escaped comma", `a\,b`, []string{"a,b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitValues(tt.cell); !slices.Equal(got, tt.values) {
+				t.Errorf("SplitValues(%q) = %q, want %q", tt.cell, got, tt.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package segments
+
+//go:generate mockgen -source svc.go -destination=../../../../../mocks/segments_mocks/svc.go -package=segments_mocks
+
+import (
+	"context"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/segments"
+)
+
+// Servicer is an interface that defines the methods that a service must implement.
+type Servicer interface {
+	GetSegments(ctx context.Context, assetID string, segmentTypes []string) ([]segments.SegmentDTO, error)
+	GetTranscription(ctx context.Context, assetID string) ([]segments.SegmentDTO, error)
+	CreateSegment(ctx context.Context, assetID string, segment segments.SegmentDTO) (segments.SegmentDTO, error)
+	UpdateSegment(ctx context.Context, assetID string, segment segments.SegmentDTO) (segments.SegmentDTO, error)
+	DeleteSegment(ctx context.Context, assetID, segmentID string) error
+	UpdateSegmentMetadata(ctx context.Context, viewID, assetID, segmentID string, payload []byte) error
+}
@@ -0,0 +1,120 @@
+package segments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/segments"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"strconv"
+	"strings"
+)
+
+// API is an interface that defines the operations that can be performed on the segments endpoints.
+type API interface {
+	GetSegments(ctx context.Context, path, assetID string, queryParams map[string]string) (segments.SegmentsDTO, error)
+	PostSegment(ctx context.Context, path, assetID string, payload []byte) (segments.SegmentDTO, error)
+	PatchSegment(ctx context.Context, path, assetID, segmentID string, payload []byte) (segments.SegmentDTO, error)
+	DeleteSegment(ctx context.Context, path, assetID, segmentID string) error
+	UpdateMetadataInSegment(ctx context.Context, path, viewID, assetID, segmentID string, payload []byte) (metadata.DTO, error)
+}
+
+// TypeTranscription is the segment type of the lines of an asset's transcript.
+const TypeTranscription = "TRANSCRIPTION"
+
+type Svc struct {
+	api API
+}
+
+// New is a function that returns a new instance of the Svc struct.
+func New(
+	api API,
+) *Svc {
+	return &Svc{
+		api: api,
+	}
+}
+
+// GetSegments gets every segment of an asset from the iconik api, in time order. If any segment
+// types are given, only segments of those types are returned.
+func (s *Svc) GetSegments(ctx context.Context, assetID string, segmentTypes []string) ([]segments.SegmentDTO, error) {
+	queryParams := map[string]string{
+		"per_page": "500",
+		"sort":     "time_start_milliseconds",
+	}
+	if len(segmentTypes) > 0 {
+		queryParams["segment_type"] = strings.Join(segmentTypes, ",")
+	}
+
+	var segs []segments.SegmentDTO
+	for pageNo := 1; ; pageNo++ {
+		queryParams["page"] = strconv.Itoa(pageNo)
+
+		dto, err := s.api.GetSegments(ctx, iconik.AssetsPath, assetID, queryParams)
+		if err != nil {
+			return nil, err
+		}
+		if dto.Errors != nil {
+			return nil, fmt.Errorf("%v", dto.Errors)
+		}
+
+		for _, seg := range dto.Objects {
+			seg.AssetID = assetID
+			segs = append(segs, seg)
+		}
+
+		if pageNo >= dto.Pages || len(dto.Objects) == 0 {
+			return segs, nil
+		}
+	}
+}
+
+// GetTranscription gets the transcript of an asset from the iconik api, which is held as a
+// TRANSCRIPTION segment for each line of speech, in time order. The text of each line is set
+// as its SegmentText.
+func (s *Svc) GetTranscription(ctx context.Context, assetID string) ([]segments.SegmentDTO, error) {
+	segs, err := s.GetSegments(ctx, assetID, []string{TypeTranscription})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, seg := range segs {
+		if seg.TranscriptionText != "" {
+			segs[i].SegmentText = seg.TranscriptionText
+		}
+	}
+
+	return segs, nil
+}
+
+// CreateSegment creates a segment of an asset in the iconik api, and returns it with its ID.
+func (s *Svc) CreateSegment(ctx context.Context, assetID string, segment segments.SegmentDTO) (segments.SegmentDTO, error) {
+	payload, err := json.Marshal(segment.ToSegment())
+	if err != nil {
+		return segments.SegmentDTO{}, err
+	}
+
+	return s.api.PostSegment(ctx, iconik.AssetsPath, assetID, payload)
+}
+
+// UpdateSegment updates the times, type and text of a segment of an asset in the iconik api.
+func (s *Svc) UpdateSegment(ctx context.Context, assetID string, segment segments.SegmentDTO) (segments.SegmentDTO, error) {
+	payload, err := json.Marshal(segment.ToSegment())
+	if err != nil {
+		return segments.SegmentDTO{}, err
+	}
+
+	return s.api.PatchSegment(ctx, iconik.AssetsPath, assetID, segment.ID, payload)
+}
+
+// DeleteSegment deletes a segment of an asset in the iconik api.
+func (s *Svc) DeleteSegment(ctx context.Context, assetID, segmentID string) error {
+	return s.api.DeleteSegment(ctx, iconik.AssetsPath, assetID, segmentID)
+}
+
+// UpdateSegmentMetadata writes the metadata values of a segment through a view in the iconik api.
+func (s *Svc) UpdateSegmentMetadata(ctx context.Context, viewID, assetID, segmentID string, payload []byte) error {
+	_, err := s.api.UpdateMetadataInSegment(ctx, iconik.MetadataAssetsPath, viewID, assetID, segmentID, payload)
+	return err
+}
@@ -0,0 +1,98 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+)
+
+// tableColumns are the columns of a table matched to the fields of a view.
+type tableColumns struct {
+	// system holds the index of the first column of each system column found.
+	system map[string]int
+	// fields holds the index of each column matching a view field, in the order of the table.
+	fields []int
+	// viewFields holds the view field matched by each column in fields.
+	viewFields map[int]metadatadomain.ViewFieldDTO
+	// nonMatching holds the labels of the columns which match no view field.
+	nonMatching []string
+	// byName is set when the table has a second header row of field names, which the columns
+	// are matched by.
+	byName bool
+	// rows are the rows of the table after its header rows.
+	rows [][]string
+}
+
+// matchColumns matches the columns of a table to the view. Columns isSystem reports true for
+// describe the asset or segment itself and are kept by name. The others are matched to view
+// fields by label, or by name when the header row of labels is followed by a row of field
+// names, which repeats the system columns. A column matching several fields is an error.
+func matchColumns(viewFields []metadatadomain.ViewFieldDTO, data [][]string, isSystem func(string) bool) (tableColumns, error) {
+	labels := data[0]
+	tc := tableColumns{
+		system:     make(map[string]int),
+		viewFields: make(map[int]metadatadomain.ViewFieldDTO),
+		rows:       data[1:],
+	}
+
+	headers := labels
+	if len(tc.rows) > 0 && isNameRow(labels, tc.rows[0], isSystem) {
+		headers, tc.rows, tc.byName = tc.rows[0], tc.rows[1:], true
+	}
+
+	for index, header := range headers {
+		if isSystem(header) {
+			if _, ok := tc.system[header]; !ok {
+				tc.system[header] = index
+			}
+			continue
+		}
+
+		var fields []metadatadomain.ViewFieldDTO
+		for _, viewField := range viewFields {
+			if viewField.Name == "__separator__" {
+				continue
+			}
+			if (tc.byName && header == viewField.Name) || (!tc.byName && header == viewField.Label) {
+				fields = append(fields, viewField)
+			}
+		}
+
+		switch len(fields) {
+		case 0:
+			label := header
+			if index < len(labels) {
+				label = labels[index]
+			}
+			tc.nonMatching = append(tc.nonMatching, label)
+		case 1:
+			tc.fields = append(tc.fields, index)
+			tc.viewFields[index] = fields[0]
+		default:
+			names := make([]string, len(fields))
+			for i, field := range fields {
+				names[i] = field.Name
+			}
+			return tableColumns{}, fmt.Errorf("column %s matches the view fields %s, export a csv or xlsx file with -field-names to match its columns by name", header, strings.Join(names, ", "))
+		}
+	}
+
+	return tc, nil
+}
+
+// isNameRow reports whether row is a header row of field names following the header row of
+// labels. The system columns are written by name in both rows, so a row of names repeats them.
+func isNameRow(labels, row []string, isSystem func(string) bool) bool {
+	found := false
+	for i, label := range labels {
+		if !isSystem(label) {
+			continue
+		}
+		if i >= len(row) || row[i] != label {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,114 @@
+package input
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+)
+
+func TestMatchColumns(t *testing.T) {
+	viewFields := []metadatadomain.ViewFieldDTO{
+		{Name: "fr", Label: "Frame Rate"},
+		{Name: "__separator__"},
+		{Name: "notes", Label: "Notes"},
+		{Name: "notes_2", Label: "Notes"},
+	}
+
+	tests := []struct {
+		name        string
+		data        [][]string
+		isSystem    func(string) bool
+		fields      []string
+		system      map[string]int
+		nonMatching []string
+		byName      bool
+		rows        int
+		err         string
+	}{
+		{
+			name:        "by label",
+			data:        [][]string{{"id", "Frame Rate", "Rating", "title"}, {"1", "25", "5", "A"}},
+			isSystem:    record.IsSystemColumn,
+			fields:      []string{"fr"},
+			system:      map[string]int{"id": 0, "title": 3},
+			nonMatching: []string{"Rating"},
+			rows:        1,
+		},
+		{
+			name:     "by name",
+			data:     [][]string{{"id", "Notes", "Notes"}, {"id", "notes", "notes_2"}, {"1", "a", "b"}},
+			isSystem: record.IsSystemColumn,
+			fields:   []string{"notes", "notes_2"},
+			system:   map[string]int{"id": 0},
+			byName:   true,
+			rows:     1,
+		},
+		{
+			name:     "first of a repeated system column",
+			data:     [][]string{{"id", "id", "Frame Rate"}, {"1", "1", "25"}, {"1", "1", "30"}},
+			isSystem: record.IsSystemColumn,
+			fields:   []string{"fr"},
+			system:   map[string]int{"id": 0},
+			rows:     2,
+		},
+		{
+			name:     "segment columns",
+			data:     [][]string{{"asset_id", "time_start", "time_end", "Frame Rate", "id"}, {"1", "0", "10", "25", "x"}},
+			isSystem: record.IsSegmentColumn,
+			fields:   []string{"fr"},
+			system:   map[string]int{"asset_id": 0, "time_start": 1, "time_end": 2},
+			// id is a column of an asset table, not of a segment table.
+			nonMatching: []string{"id"},
+			rows:        1,
+		},
+		{
+			name:     "label shared by several fields",
+			data:     [][]string{{"id", "Notes"}, {"1", "a"}},
+			isSystem: record.IsSystemColumn,
+			err:      "column Notes matches the view fields notes, notes_2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := matchColumns(viewFields, tt.data, tt.isSystem)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("matchColumns() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fields []string
+			for _, index := range columns.fields {
+				fields = append(fields, columns.viewFields[index].Name)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", fields, tt.fields)
+			}
+			if len(columns.system) != len(tt.system) {
+				t.Errorf("system = %v, want %v", columns.system, tt.system)
+			}
+			for name, index := range tt.system {
+				if columns.system[name] != index {
+					t.Errorf("system[%s] = %d, want %d", name, columns.system[name], index)
+				}
+			}
+			if !slices.Equal(columns.nonMatching, tt.nonMatching) {
+				t.Errorf("nonMatching = %v, want %v", columns.nonMatching, tt.nonMatching)
+			}
+			if columns.byName != tt.byName {
+				t.Errorf("byName = %v, want %v", columns.byName, tt.byName)
+			}
+			if len(columns.rows) != tt.rows {
+				t.Errorf("rows = %d, want %d", len(columns.rows), tt.rows)
+			}
+		})
+	}
+}
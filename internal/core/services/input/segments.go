@@ -0,0 +1,172 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/segments"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/timecode"
+	"github.com/base-media-cloud/pd-iconik-io-rd/utils"
+)
+
+// Segment is a single time based segment of an asset as it is read from a file, with the metadata
+// values written to it.
+type Segment struct {
+	segments.SegmentDTO
+	metadatadomain.Values
+}
+
+// IsSegmentTable reports whether header is the header row of a table with a row for each segment
+// of an asset.
+func IsSegmentTable(header []string) bool {
+	return slices.Contains(header, "asset_id") && slices.Contains(header, "time_start") && slices.Contains(header, "time_end")
+}
+
+// SegmentsFromTable reads the rows of a segment table into segments. Times are read as
+// milliseconds or as timecodes at fps frames per second. Segments without a type are GENERIC,
+// and segments without an end time end where they start. The other columns are matched to view
+// fields by label, or by name when the file has a second header row of field names, and their
+// labels are returned if they match none.
+func (svc *Svc) SegmentsFromTable(viewFields []metadatadomain.ViewFieldDTO, data [][]string, fps float64) ([]Segment, []string, error) {
+	columns, err := matchColumns(viewFields, data, record.IsSegmentColumn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cell := func(row []string, name string) string {
+		i, ok := columns.system[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	first := 2
+	if columns.byName {
+		first = 3
+	}
+
+	result := make([]Segment, 0, len(columns.rows))
+	for r, row := range columns.rows {
+		line := r + first
+
+		seg := Segment{Values: metadatadomain.NewValues()}
+		seg.AssetID = cell(row, "asset_id")
+		if seg.AssetID == "" {
+			continue
+		}
+		seg.ID = cell(row, "segment_id")
+		seg.SegmentText = cell(row, "text")
+
+		seg.SegmentType = strings.ToUpper(cell(row, "segment_type"))
+		if seg.SegmentType == "" {
+			seg.SegmentType = "GENERIC"
+		}
+
+		start, err := timecode.Parse(cell(row, "time_start"), fps)
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: time_start: %w", line, err)
+		}
+		end := start
+		if s := cell(row, "time_end"); s != "" {
+			if end, err = timecode.Parse(s, fps); err != nil {
+				return nil, nil, fmt.Errorf("row %d: time_end: %w", line, err)
+			}
+		}
+		if end < start {
+			return nil, nil, fmt.Errorf("row %d: time_end is before time_start", line)
+		}
+		seg.TimeStartMilliseconds, seg.TimeEndMilliseconds = start, end
+
+		for _, index := range columns.fields {
+			field := columns.viewFields[index]
+			// an empty cell is a field without values, as it is exported.
+			if index >= len(row) || row[index] == "" {
+				seg.Set(field.Name)
+				continue
+			}
+
+			valueArr := record.SplitValues(row[index])
+			values := make([]interface{}, 0, len(valueArr))
+			for _, val := range valueArr {
+				if err := utils.ValidateSchema(field.Label, val); err != nil {
+					return nil, nil, fmt.Errorf("row %d: %w", line, err)
+				}
+				values = append(values, val)
+			}
+			seg.Set(field.Name, values...)
+		}
+
+		result = append(result, seg)
+	}
+
+	return result, columns.nonMatching, nil
+}
+
+// ProcessSegments writes each segment to its asset in iconik, updating the segment if it has an
+// ID and creating it otherwise, then writes its metadata values. Assets must be within the
+// collection. The numbers of segments created and updated are returned, with the asset IDs of the
+// segments which could not be matched to an asset.
+func (svc *Svc) ProcessSegments(ctx context.Context, segs []Segment, collectionID string, views []metadatadomain.DTO) (int, int, map[string]bool, error) {
+	var created, updated int
+	notAdded := make(map[string]bool)
+	found := make(map[string]bool)
+
+	for _, seg := range segs {
+		if notAdded[seg.AssetID] {
+			continue
+		}
+		if !found[seg.AssetID] {
+			if _, err := svc.searchSvc.ValidateAndSearchAssetID(ctx, seg.AssetID, collectionID); err != nil {
+				log.Printf("%s for asset %s, skipping its segments\n", err, seg.AssetID)
+				notAdded[seg.AssetID] = true
+				continue
+			}
+			found[seg.AssetID] = true
+		}
+
+		var written segments.SegmentDTO
+		var err error
+		if seg.ID != "" {
+			written, err = svc.segmentSvc.UpdateSegment(ctx, seg.AssetID, seg.SegmentDTO)
+			updated++
+		} else {
+			written, err = svc.segmentSvc.CreateSegment(ctx, seg.AssetID, seg.SegmentDTO)
+			created++
+		}
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		segmentID := written.ID
+		if segmentID == "" {
+			segmentID = seg.ID
+		}
+		if segmentID == "" {
+			return 0, 0, nil, fmt.Errorf("no segment ID returned for asset %s", seg.AssetID)
+		}
+
+		for i, values := range splitValues(seg.Values, views) {
+			if len(values.MetadataValues) == 0 {
+				continue
+			}
+
+			metadataPayload, err := json.Marshal(values)
+			if err != nil {
+				return 0, 0, nil, errors.New("error marshaling JSON")
+			}
+
+			if err = svc.segmentSvc.UpdateSegmentMetadata(ctx, views[i].ID, seg.AssetID, segmentID, metadataPayload); err != nil {
+				return 0, 0, nil, err
+			}
+		}
+	}
+
+	return created, updated, notAdded, nil
+}
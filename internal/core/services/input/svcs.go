@@ -1,21 +1,32 @@
 package input
 
 import (
+	"bufio"
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/ale"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
 	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/assets/assets"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/assets/collections"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/assets/segments"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/metadata"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/csvio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xmp"
 	"github.com/base-media-cloud/pd-iconik-io-rd/utils"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -25,6 +36,7 @@ type Svc struct {
 	assetSvc    assets.Servicer
 	metadataSvc metadata.Servicer
 	searchSvc   search.Servicer
+	segmentSvc  segments.Servicer
 }
 
 // New is a function that returns a new instance of the iconik Svc struct.
@@ -33,94 +45,268 @@ func New(
 	assetSvc assets.Servicer,
 	metadataSvc metadata.Servicer,
 	searchSvc search.Servicer,
+	segmentSvc segments.Servicer,
 ) *Svc {
 	return &Svc{
 		collSvc:     collSvc,
 		assetSvc:    assetSvc,
 		metadataSvc: metadataSvc,
 		searchSvc:   searchSvc,
+		segmentSvc:  segmentSvc,
 	}
 }
 
-func (svc *Svc) ProcessAssets(ctx context.Context, csvData [][]string, collectionID, viewID string) (map[string]bool, error) {
+// RecordsFromCSV converts the rows of a matched CSV into records, splitting cells into multiple
+// values on the commas not escaped by a backslash and validating them. Empty cells clear their
+// field.
+func (svc *Svc) RecordsFromCSV(csvData [][]string) ([]record.Record, error) {
 	matchingFileHeaderNames := csvData[0]
 	matchingFileHeaderLabels := csvData[1]
-	notAdded := make(map[string]bool)
 
+	records := make([]record.Record, 0, len(csvData)-2)
 	for i := 2; i < len(csvData); i++ {
 		row := csvData[i]
-		assetID := row[0]
-		origName := row[1]
-		title := row[3]
-
-		_, errAssetID := svc.searchSvc.ValidateAndSearchAssetID(ctx, assetID, collectionID)
-		if errAssetID != nil {
-			result, errFilename := svc.searchSvc.ValidateAndSearchFilename(ctx, origName, collectionID)
-			if errFilename != nil {
-				log.Printf("%s & %s for %s, skipping\n", errAssetID, errFilename, title)
-				notAdded[assetID] = true
+		rec := record.New(row[0], row[1], row[3])
+		rec.ObjectType = row[4]
+
+		for count := 5; count < len(row); count++ {
+			headerName := matchingFileHeaderNames[count]
+			headerLabel := matchingFileHeaderLabels[count]
+
+			// an empty cell is a field without values, as it is exported.
+			if row[count] == "" {
+				rec.Set(headerName)
 				continue
 			}
-			assetID = result.ID
+
+			valueArr := record.SplitValues(row[count])
+			values := make([]interface{}, 0, len(valueArr))
+			for _, val := range valueArr {
+				if err := utils.ValidateSchema(headerLabel, val); err != nil {
+					return nil, err
+				}
+				values = append(values, val)
+			}
+			rec.Set(headerName, values...)
 		}
 
-		metadataValues := metadatadomain.Values{
-			MetadataValues: map[string]struct {
-				FieldValues []metadatadomain.FieldValue `json:"field_values"`
-			}(make(map[string]struct {
-				FieldValues []metadatadomain.FieldValue
-			})),
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// IsLongTable reports whether header is the header row of a table in the long layout, which
+// has a row for each value of each field of an asset.
+func IsLongTable(header []string) bool {
+	return slices.Contains(header, "asset_id") && slices.Contains(header, "field_name") && slices.Contains(header, "value")
+}
+
+// RecordsFromLongTable pivots the rows of a table in the long layout back into a record for each
+// asset, matching fields to the view by name. The values of a field are ordered by their
+// value_index column if there is one, otherwise by their order in the file, and a field with only
+// empty values is cleared. The names of fields which are not in the view are returned.
+func (svc *Svc) RecordsFromLongTable(viewFields []metadatadomain.ViewFieldDTO, data [][]string) ([]record.Record, []string, error) {
+	columns := make(map[string]int)
+	for i, c := range data[0] {
+		if _, ok := columns[c]; !ok {
+			columns[c] = i
 		}
+	}
+	cell := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
 
-		for count := 4; count < len(row); count++ {
-			headerName := matchingFileHeaderNames[count]
-			headerLabel := matchingFileHeaderLabels[count]
-			fieldValueSlice := make([]metadatadomain.FieldValue, 0)
+	labels := make(map[string]string, len(viewFields))
+	for _, viewField := range viewFields {
+		if viewField.Name != "__separator__" {
+			labels[viewField.Name] = viewField.Label
+		}
+	}
 
-			valueArr := strings.Split(row[count], ",")
-			if len(valueArr) > 0 {
-				for _, val := range valueArr {
-					err := utils.ValidateSchema(headerLabel, val)
-					if err != nil {
-						return nil, err
-					}
+	type value struct {
+		index int
+		val   string
+	}
 
-					fieldValueSlice = append(fieldValueSlice, metadatadomain.FieldValue{Value: val})
-				}
-				metadataValues.MetadataValues[headerName] = struct {
-					FieldValues []metadatadomain.FieldValue `json:"field_values"`
-				}{
-					FieldValues: fieldValueSlice,
-				}
+	var ids []string
+	var nonMatchingNames []string
+	records := make(map[string]*record.Record)
+	values := make(map[string]map[string][]value)
+	seen := make(map[string]bool)
+
+	for i, row := range data[1:] {
+		id, name := cell(row, "asset_id"), cell(row, "field_name")
+		if id == "" {
+			continue
+		}
+
+		if _, ok := records[id]; !ok {
+			rec := record.New(id, "", "")
+			rec.ObjectType = cell(row, "object_type")
+			records[id] = &rec
+			values[id] = make(map[string][]value)
+			ids = append(ids, id)
+		}
+
+		label, ok := labels[name]
+		if !ok {
+			if !seen[name] {
+				seen[name] = true
+				nonMatchingNames = append(nonMatchingNames, name)
 			}
+			continue
 		}
 
-		assetPayload, err := json.Marshal(map[string]string{"title": title})
-		if err != nil {
-			return nil, errors.New("error marshaling JSON")
+		// an empty value clears the field, as an empty cell of a wide table does.
+		val := cell(row, "value")
+		if val == "" {
+			if _, ok := values[id][name]; !ok {
+				values[id][name] = []value{}
+			}
+			continue
+		}
+		if err := utils.ValidateSchema(label, val); err != nil {
+			return nil, nil, fmt.Errorf("row %d: %w", i+2, err)
 		}
 
-		_, err = svc.assetSvc.UpdateAsset(ctx, iconik.AssetsPath, assetID, assetPayload)
-		if err != nil {
-			log.Println("Error updating title for asset ", assetID)
-			return nil, err
+		index := len(values[id][name])
+		if s := cell(row, "value_index"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: invalid value_index %s", i+2, s)
+			}
+			index = n
 		}
+		values[id][name] = append(values[id][name], value{index: index, val: val})
+	}
 
-		metadataPayload, err := json.Marshal(metadataValues)
-		if err != nil {
-			return nil, errors.New("error marshaling JSON")
+	result := make([]record.Record, 0, len(ids))
+	for _, id := range ids {
+		rec := records[id]
+		for name, vals := range values[id] {
+			sort.SliceStable(vals, func(i, j int) bool { return vals[i].index < vals[j].index })
+			fieldValues := make([]interface{}, len(vals))
+			for i, v := range vals {
+				fieldValues[i] = v.val
+			}
+			rec.Set(name, fieldValues...)
 		}
+		result = append(result, *rec)
+	}
 
-		_, err = svc.metadataSvc.UpdateMetadataInAsset(ctx, iconik.MetadataAssetsPath, viewID, assetID, metadataPayload)
-		if err != nil {
-			return nil, err
+	sort.Strings(nonMatchingNames)
+
+	return result, nonMatchingNames, nil
+}
+
+// ProcessRecords writes the title and metadata values of each record to its asset or collection
+// in iconik. Assets are looked up by ID, falling back to their original filename, and
+// collections by ID. Records without a title leave the title unchanged. The IDs of the records
+// which could not be matched to an asset or collection are returned.
+func (svc *Svc) ProcessRecords(ctx context.Context, records []record.Record, collectionID string, views []metadatadomain.DTO) (map[string]bool, error) {
+	notAdded := make(map[string]bool)
+
+	for _, rec := range records {
+		objectPath, metadataPath := iconik.AssetsPath, iconik.MetadataAssetsPath
+
+		var objectID string
+		switch rec.ObjectType {
+		case record.ObjectTypeCollections:
+			if _, err := svc.collSvc.GetCollection(ctx, iconik.CollectionsPath, rec.ID); err != nil {
+				log.Printf("%s for collection %s, skipping\n", err, rec.Title)
+				notAdded[rec.ID] = true
+				continue
+			}
+			objectID = rec.ID
+			objectPath, metadataPath = iconik.CollectionsPath, iconik.MetadataCollectionsPath
+		case "", record.ObjectTypeAssets:
+			assetID, err := svc.findAsset(ctx, rec, collectionID)
+			if err != nil {
+				log.Printf("%s for %s, skipping\n", err, rec.Title)
+				notAdded[rec.ID] = true
+				continue
+			}
+			objectID = assetID
+		default:
+			return nil, fmt.Errorf("unknown object type %s for %s", rec.ObjectType, rec.ID)
 		}
 
+		if rec.Title != "" {
+			assetPayload, err := json.Marshal(map[string]string{"title": rec.Title})
+			if err != nil {
+				return nil, errors.New("error marshaling JSON")
+			}
+
+			_, err = svc.assetSvc.UpdateAsset(ctx, objectPath, objectID, assetPayload)
+			if err != nil {
+				log.Println("Error updating title for ", objectID)
+				return nil, err
+			}
+		}
+
+		for i, values := range splitValues(rec.Values, views) {
+			if len(views) > 1 && len(values.MetadataValues) == 0 {
+				continue
+			}
+
+			metadataPayload, err := json.Marshal(values)
+			if err != nil {
+				return nil, errors.New("error marshaling JSON")
+			}
+
+			_, err = svc.metadataSvc.UpdateMetadataInAsset(ctx, metadataPath, views[i].ID, objectID, metadataPayload)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return notAdded, nil
 }
 
+// splitValues splits values into the values written through each view. A field in several views
+// is written through the first of them.
+func splitValues(values metadatadomain.Values, views []metadatadomain.DTO) []metadatadomain.Values {
+	if len(views) == 1 {
+		return []metadatadomain.Values{values}
+	}
+
+	split := make([]metadatadomain.Values, len(views))
+	written := make(map[string]bool)
+	for i, view := range views {
+		split[i] = values.Only(view)
+		for name := range split[i].MetadataValues {
+			if written[name] {
+				delete(split[i].MetadataValues, name)
+			}
+			written[name] = true
+		}
+	}
+
+	return split
+}
+
+// findAsset returns the ID of the asset of rec within the collection, looked up by ID and
+// falling back to its original filename.
+func (svc *Svc) findAsset(ctx context.Context, rec record.Record, collectionID string) (string, error) {
+	_, errAssetID := svc.searchSvc.ValidateAndSearchAssetID(ctx, rec.ID, collectionID)
+	if errAssetID == nil {
+		return rec.ID, nil
+	}
+
+	result, errFilename := svc.searchSvc.ValidateAndSearchFilename(ctx, rec.OriginalName, collectionID)
+	if errFilename != nil {
+		return "", fmt.Errorf("%s & %s", errAssetID, errFilename)
+	}
+
+	return result.ID, nil
+}
+
 // GetMetadataView retrieves a Metadata view from the iconik API.
 func (svc *Svc) GetMetadataView(ctx context.Context, viewID string) (metadatadomain.DTO, error) {
 	view, err := svc.metadataSvc.GetMetadataView(ctx, iconik.MetadataViewPath, viewID)
@@ -131,10 +317,39 @@ func (svc *Svc) GetMetadataView(ctx context.Context, viewID string) (metadatadom
 	if view.Errors != nil {
 		return metadatadomain.DTO{}, fmt.Errorf("%v", view.Errors)
 	}
+	view.ID = viewID
 
 	return view, nil
 }
 
+// GetMetadataViews retrieves each of the Metadata views from the iconik API.
+func (svc *Svc) GetMetadataViews(ctx context.Context, viewIDs []string) ([]metadatadomain.DTO, error) {
+	views := make([]metadatadomain.DTO, 0, len(viewIDs))
+	for _, viewID := range viewIDs {
+		view, err := svc.GetMetadataView(ctx, viewID)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %w", viewID, err)
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
+// ReadFile reads the input file in the selected format and returns it as a 2D slice.
+func (svc *Svc) ReadFile(appCfg *config.App) ([][]string, error) {
+	switch appCfg.FileFormat() {
+	case config.FormatCSV:
+		return svc.ReadCSVFile(appCfg)
+	case config.FormatXLSX:
+		return svc.ReadXLSXFile(appCfg)
+	case config.FormatALE:
+		return svc.ReadALEFile(appCfg)
+	}
+
+	return nil, fmt.Errorf("unsupported input format %s", appCfg.FileFormat())
+}
+
 // ReadCSVFile reads a CSV file and returns it as a 2D slice.
 func (svc *Svc) ReadCSVFile(appCfg *config.App) ([][]string, error) {
 	csvFile, err := os.Open(appCfg.Input)
@@ -143,7 +358,10 @@ func (svc *Svc) ReadCSVFile(appCfg *config.App) ([][]string, error) {
 	}
 	defer csvFile.Close()
 
-	csvReader := csv.NewReader(csvFile)
+	csvReader, err := csvio.NewReader(csvFile, appCfg.CSVDialect())
+	if err != nil {
+		return nil, err
+	}
 
 	csvData, err := csvReader.ReadAll()
 	if err != nil {
@@ -153,64 +371,316 @@ func (svc *Svc) ReadCSVFile(appCfg *config.App) ([][]string, error) {
 	return csvData, nil
 }
 
-// MatchCSVtoView takes a csv as a 2d slice, and checks its fields against the inputted view field from iconik.
-func (svc *Svc) MatchCSVtoView(viewFields []metadatadomain.ViewFieldDTO, csvData [][]string) ([][]string, []string, error) {
-	csvHeaderLabels := csvData[0]
-
-	var matchingIconikHeaderNames []string
-	var matchingIconikHeaderLabels []string
-	matchingIconikHeaderNames = append(matchingIconikHeaderNames, "id")
-	matchingIconikHeaderNames = append(matchingIconikHeaderNames, "original_name")
-	matchingIconikHeaderNames = append(matchingIconikHeaderNames, "size")
-	matchingIconikHeaderNames = append(matchingIconikHeaderNames, "title")
-	matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, "id")
-	matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, "original_name")
-	matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, "size")
-	matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, "title")
-
-	var nonMatchingHeaders []string
-
-	for index, csvHeaderLabel := range csvHeaderLabels {
-		if index > 3 {
-			found := false
-			for _, viewField := range viewFields {
-				if csvHeaderLabel == viewField.Label {
-					matchingIconikHeaderNames = append(matchingIconikHeaderNames, viewField.Name)
-					matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, viewField.Label)
-					found = true
-					break
+// ReadXLSXFile reads a sheet of an Excel workbook and returns it as a 2D slice.
+func (svc *Svc) ReadXLSXFile(appCfg *config.App) ([][]string, error) {
+	xlsxFile, err := os.Open(appCfg.Input)
+	if err != nil {
+		return nil, err
+	}
+	defer xlsxFile.Close()
+
+	return xlsxio.ReadAll(xlsxFile, appCfg.Sheet)
+}
+
+// ReadALEFile reads an Avid Log Exchange file and returns it as a 2D slice with the same
+// leading columns as a CSV. Clips are matched to assets by their iconik ID column if they
+// have one, otherwise by their source file name, or tape name if there is no source file.
+// The clip name is used as the asset title.
+func (svc *Svc) ReadALEFile(appCfg *config.App) ([][]string, error) {
+	aleFile, err := os.Open(appCfg.Input)
+	if err != nil {
+		return nil, err
+	}
+	defer aleFile.Close()
+
+	aleData, err := ale.ReadAll(aleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := aleData[0]
+	index := func(name string) int {
+		for i, col := range columns {
+			if strings.EqualFold(col, name) {
+				return i
+			}
+		}
+		return -1
+	}
+	idCol, nameCol := index(ale.ColumnID), index(ale.ColumnName)
+	sourceCol, tapeCol := index(ale.ColumnSourceFile), index(ale.ColumnTape)
+
+	cell := func(row []string, i int) string {
+		if i < 0 {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var otherCols []int
+	header := []string{"id", "original_name", "size", "title"}
+	for i, col := range columns {
+		if i == idCol || i == nameCol || i == sourceCol || i == tapeCol {
+			continue
+		}
+		otherCols = append(otherCols, i)
+		header = append(header, col)
+	}
+
+	csvData := [][]string{header}
+	for _, row := range aleData[1:] {
+		origName := cell(row, sourceCol)
+		if origName == "" {
+			origName = cell(row, tapeCol)
+		}
+
+		csvRow := []string{cell(row, idCol), origName, "", cell(row, nameCol)}
+		for _, i := range otherCols {
+			csvRow = append(csvRow, row[i])
+		}
+		csvData = append(csvData, csvRow)
+	}
+
+	return csvData, nil
+}
+
+// ReadJSONFile reads a JSON file holding an array of records, or an NDJSON file holding
+// one record per line, and returns its records.
+func (svc *Svc) ReadJSONFile(appCfg *config.App) ([]record.Record, error) {
+	jsonFile, err := os.Open(appCfg.Input)
+	if err != nil {
+		return nil, err
+	}
+	defer jsonFile.Close()
+
+	br := bufio.NewReader(jsonFile)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+
+	var records []record.Record
+	if first, err := firstNonSpace(br); err == nil && first == '[' {
+		if err = dec.Decode(&records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+
+	for {
+		var rec record.Record
+		err = dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// ReadXMPFiles reads an XMP sidecar file, or every .xmp file in a folder, and returns a record
+// for each. Properties are mapped back to view fields through the mapping, and sidecars are
+// matched to assets by their custom ID and original filename properties if they have them,
+// otherwise by the name of the sidecar itself.
+func (svc *Svc) ReadXMPFiles(appCfg *config.App) ([]record.Record, error) {
+	mapping, err := xmp.LoadMapping(appCfg.XMPMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{appCfg.Input}
+	if info, err := os.Stat(appCfg.Input); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(appCfg.Input)
+		if err != nil {
+			return nil, err
+		}
+		paths = paths[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".xmp") {
+				paths = append(paths, filepath.Join(appCfg.Input, entry.Name()))
+			}
+		}
+	}
+
+	records := make([]record.Record, 0, len(paths))
+	for _, path := range paths {
+		rec, err := readXMPFile(path, mapping)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func readXMPFile(path string, mapping xmp.Mapping) (record.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return record.Record{}, err
+	}
+	defer f.Close()
+
+	props, err := xmp.Read(f)
+	if err != nil {
+		return record.Record{}, err
+	}
+
+	base := filepath.Base(path)
+	rec := record.New("", strings.TrimSuffix(base, filepath.Ext(base)), "")
+	for _, p := range props {
+		switch {
+		case mapping.Is(p, "dc", "title") && len(p.Values) > 0:
+			rec.Title = p.Values[0]
+			continue
+		case mapping.Is(p, mapping.Namespace.Prefix, xmp.PropertyID) && len(p.Values) > 0:
+			rec.ID = p.Values[0]
+			continue
+		case mapping.Is(p, mapping.Namespace.Prefix, xmp.PropertyOriginalName) && len(p.Values) > 0:
+			rec.OriginalName = p.Values[0]
+			continue
+		}
+
+		field, ok := mapping.Field(p)
+		if !ok {
+			continue
+		}
+
+		values := make([]interface{}, len(p.Values))
+		for i, v := range p.Values {
+			values[i] = v
+		}
+		rec.Set(field, values...)
+	}
+
+	return rec, nil
+}
+
+// firstNonSpace returns the first non-whitespace byte of br without consuming it.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return 0, err
+		}
+		if c := b[i-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c, nil
+		}
+	}
+}
+
+// MatchRecordsToView removes the metadata values of fields which aren't part of the view from
+// each record, and validates the values of those that are. The names of the removed fields are
+// returned.
+func (svc *Svc) MatchRecordsToView(viewFields []metadatadomain.ViewFieldDTO, records []record.Record) ([]record.Record, []string, error) {
+	labels := make(map[string]string, len(viewFields))
+	for _, viewField := range viewFields {
+		labels[viewField.Name] = viewField.Label
+	}
+
+	var nonMatchingNames []string
+	seen := make(map[string]bool)
+
+	for i, rec := range records {
+		if rec.MetadataValues == nil {
+			records[i].Values = metadatadomain.NewValues()
+			continue
+		}
+
+		for name, fieldValues := range rec.MetadataValues {
+			label, ok := labels[name]
+			if !ok {
+				delete(rec.MetadataValues, name)
+				if !seen[name] {
+					seen[name] = true
+					nonMatchingNames = append(nonMatchingNames, name)
 				}
+				continue
 			}
-			if !found {
-				nonMatchingHeaders = append(nonMatchingHeaders, csvHeaderLabel)
+
+			for _, fieldValue := range fieldValues.FieldValues {
+				var str string
+				switch val := fieldValue.Value.(type) {
+				case string:
+					str = val
+				case json.Number:
+					str = val.String()
+				case bool:
+					str = strconv.FormatBool(val)
+				default:
+					continue
+				}
+				if err := utils.ValidateSchema(label, str); err != nil {
+					return nil, nil, fmt.Errorf("record %d: %w", i+1, err)
+				}
 			}
 		}
 	}
 
+	sort.Strings(nonMatchingNames)
+
+	return records, nonMatchingNames, nil
+}
+
+// MatchCSVtoView takes a csv as a 2d slice, and checks its fields against the inputted view field from iconik.
+// Columns are matched to view fields by label, or by name when the file has a second header row
+// of field names. The id, original_name, size, title and object_type system columns are moved to the front
+// of the returned slice, and the other system columns are dropped. Rows repeating an asset ID,
+// as written for each file of an asset, are dropped too.
+func (svc *Svc) MatchCSVtoView(viewFields []metadatadomain.ViewFieldDTO, csvData [][]string) ([][]string, []string, error) {
+	columns, err := matchColumns(viewFields, csvData, record.IsSystemColumn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matchingIconikHeaderNames := []string{"id", "original_name", "size", "title", "object_type"}
+	matchingIconikHeaderLabels := []string{"id", "original_name", "size", "title", "object_type"}
+	for _, index := range columns.fields {
+		matchingIconikHeaderNames = append(matchingIconikHeaderNames, columns.viewFields[index].Name)
+		matchingIconikHeaderLabels = append(matchingIconikHeaderLabels, columns.viewFields[index].Label)
+	}
+
+	cell := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+	systemCell := func(row []string, name string) string {
+		i, ok := columns.system[name]
+		if !ok {
+			return ""
+		}
+		// assets without files are exported with N/A as their filename and size.
+		if val := cell(row, i); val != "N/A" {
+			return val
+		}
+		return ""
+	}
+
 	var matchingValues [][]string
 	matchingValues = append(matchingValues, matchingIconikHeaderNames)
 	matchingValues = append(matchingValues, matchingIconikHeaderLabels)
 
-	for j := 1; j < len(csvData); j++ {
-		row := csvData[j]
-		var matchingRow []string
-		for k, csvHeaderLabel := range csvHeaderLabels {
-			if contains(matchingIconikHeaderLabels, csvHeaderLabel) {
-				matchingRow = append(matchingRow, row[k])
+	seen := make(map[string]bool)
+	for _, row := range columns.rows {
+		id := systemCell(row, "id")
+		if id != "" {
+			if seen[id] {
+				continue
 			}
+			seen[id] = true
+		}
+
+		matchingRow := []string{id, systemCell(row, "original_name"), systemCell(row, "size"), systemCell(row, "title"), systemCell(row, "object_type")}
+		for _, i := range columns.fields {
+			matchingRow = append(matchingRow, cell(row, i))
 		}
 		matchingValues = append(matchingValues, matchingRow)
 	}
 
-	return matchingValues, nonMatchingHeaders, nil
-
-}
+	return matchingValues, columns.nonMatching, nil
 
-func contains(slice []string, value string) bool {
-	for _, item := range slice {
-		if item == value {
-			return true
-		}
-	}
-	return false
 }
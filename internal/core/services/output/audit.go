@@ -0,0 +1,261 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// auditColumns are the columns of the audit report, which has a row for each asset.
+var auditColumns = []string{"id", "title", "original_name", "completeness", "missing_required", "not_in_options", "invalid_type"}
+
+// Audit is the outcome of checking the metadata of a set of assets against a view.
+type Audit struct {
+	// Assets is the number of assets checked.
+	Assets int
+	// Complete is the number of assets with a value for every required field.
+	Complete int
+	// Fields holds the counts for each field of the view, in view order.
+	Fields []FieldAudit
+
+	score float64
+}
+
+// FieldAudit counts the values of a single view field across the assets checked.
+type FieldAudit struct {
+	Name     string
+	Label    string
+	Required bool
+	// Filled is the number of assets with a value for the field.
+	Filled int
+	// NotInOptions is the number of values which aren't among the options of the field.
+	NotInOptions int
+	// InvalidType is the number of values which aren't of the type of the field.
+	InvalidType int
+}
+
+// Completeness returns the average share of view fields the assets hold a value for, as a
+// percentage.
+func (a *Audit) Completeness() float64 {
+	if a.Assets == 0 {
+		return 0
+	}
+	return a.score / float64(a.Assets)
+}
+
+// WriteSummary writes the audit as a human readable summary, with the fill rate and problems
+// found for each field.
+func (a *Audit) WriteSummary(w io.Writer) error {
+	fmt.Fprintf(w, "Assets audited: %d\n", a.Assets)
+	fmt.Fprintf(w, "Assets with every required field: %d (%s)\n", a.Complete, percent(a.Complete, a.Assets))
+	fmt.Fprintf(w, "Average completeness: %.1f%%\n\n", a.Completeness())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Field\tRequired\tFilled\tFill rate\tNot in options\tInvalid type")
+	for _, f := range a.Fields {
+		required := ""
+		if f.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%d\n", f.Label, required, f.Filled, percent(f.Filled, a.Assets), f.NotInOptions, f.InvalidType)
+	}
+
+	return tw.Flush()
+}
+
+// percent formats n of total as a percentage.
+func percent(n, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)*100/float64(total))
+}
+
+// AuditWriter checks the metadata of each asset in the search results against the view,
+// writing a report row for each asset and keeping the counts for the whole Audit. A field
+// is filled when it holds any value, and the completeness of an asset is the share of the
+// view's fields it fills.
+type AuditWriter struct {
+	w     RowWriter
+	audit Audit
+}
+
+// NewAuditWriter returns a new AuditWriter which writes the report rows to w. If w is an
+// io.Closer, it is closed when the AuditWriter is closed.
+func (svc *Svc) NewAuditWriter(w RowWriter) *AuditWriter {
+	return &AuditWriter{w: w}
+}
+
+// WriteHeader writes the header row of the report, and starts counting the fields of the view.
+func (aw *AuditWriter) WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error {
+	for _, field := range viewFields {
+		if field.Name == "__separator__" {
+			continue
+		}
+		aw.audit.Fields = append(aw.audit.Fields, FieldAudit{Name: field.Name, Label: field.Label, Required: field.Required})
+	}
+
+	return aw.w.WriteAll([][]string{auditColumns})
+}
+
+// WriteObjects checks each asset and writes its report row. Collections are not audited.
+func (aw *AuditWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	fields := make(map[string]metadatadomain.ViewFieldDTO, len(viewFields))
+	for _, field := range viewFields {
+		fields[field.Name] = field
+	}
+
+	rows := make([][]string, 0, len(objs))
+	for _, obj := range objs {
+		if obj.ObjectType == "collections" {
+			continue
+		}
+		rows = append(rows, aw.check(obj, fields))
+	}
+
+	return aw.w.WriteAll(rows)
+}
+
+// check counts the field values of obj and returns its report row.
+func (aw *AuditWriter) check(obj searchdomain.ObjectDTO, fields map[string]metadatadomain.ViewFieldDTO) []string {
+	var filled int
+	var missing, notInOptions, invalid []string
+
+	for i := range aw.audit.Fields {
+		fa := &aw.audit.Fields[i]
+		field := fields[fa.Name]
+
+		values := obj.Metadata[fa.Name]
+		values = slices.DeleteFunc(slices.Clone(values), func(v interface{}) bool {
+			return formatValue(v, field.FieldType, time.UTC) == ""
+		})
+		if len(values) == 0 {
+			if fa.Required {
+				missing = append(missing, fa.Label)
+			}
+			continue
+		}
+		filled++
+		fa.Filled++
+
+		for _, v := range values {
+			s := formatValue(v, field.FieldType, time.UTC)
+			if !validType(v, field.FieldType) {
+				fa.InvalidType++
+				invalid = append(invalid, fa.Label+": "+s)
+				continue
+			}
+			if !inOptions(s, field.Options) {
+				fa.NotInOptions++
+				notInOptions = append(notInOptions, fa.Label+": "+s)
+			}
+		}
+	}
+
+	aw.audit.Assets++
+	if len(missing) == 0 {
+		aw.audit.Complete++
+	}
+	completeness := 100.0
+	if len(aw.audit.Fields) > 0 {
+		completeness = float64(filled) * 100 / float64(len(aw.audit.Fields))
+	}
+	aw.audit.score += completeness
+
+	originalName := ""
+	if len(obj.Files) > 0 {
+		originalName = obj.Files[0].OriginalName
+	}
+
+	return []string{
+		obj.ID,
+		obj.Title,
+		originalName,
+		strconv.FormatFloat(completeness, 'f', 1, 64),
+		strings.Join(missing, "; "),
+		strings.Join(notInOptions, "; "),
+		strings.Join(invalid, "; "),
+	}
+}
+
+// Audit returns the counts of the assets checked so far.
+func (aw *AuditWriter) Audit() *Audit {
+	return &aw.audit
+}
+
+// Close closes the underlying RowWriter if it needs closing.
+func (aw *AuditWriter) Close() error {
+	if c, ok := aw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// validType reports whether the value v is of the view field type fieldType. Values of
+// types which aren't checked are always valid.
+func validType(v interface{}, fieldType string) bool {
+	switch fieldType {
+	case "integer":
+		switch val := v.(type) {
+		case float64:
+			return val == float64(int64(val))
+		case json.Number:
+			_, err := val.Int64()
+			return err == nil
+		case string:
+			_, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+			return err == nil
+		}
+		return false
+	case "float":
+		switch val := v.(type) {
+		case float64:
+			return true
+		case json.Number:
+			_, err := val.Float64()
+			return err == nil
+		case string:
+			_, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+			return err == nil
+		}
+		return false
+	case "boolean":
+		switch val := v.(type) {
+		case bool:
+			return true
+		case string:
+			_, err := strconv.ParseBool(strings.TrimSpace(val))
+			return err == nil
+		}
+		return false
+	case "date", "datetime":
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		s = strings.TrimSpace(s)
+		if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return true
+		}
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	}
+	return true
+}
+
+// inOptions reports whether the value is one of the options of its field. Fields without
+// options take any value.
+func inOptions(value string, options []metadatadomain.OptionDTO) bool {
+	if len(options) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(options, func(opt metadatadomain.OptionDTO) bool { return opt.Value == value })
+}
@@ -0,0 +1,235 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+)
+
+// CollectionPathColumn is the system column holding the subcollection of each asset, which is
+// resolved through the collections API rather than read from the search results.
+const CollectionPathColumn = "collection_path"
+
+// DefaultColumns are the system columns written before the view fields unless others are chosen.
+var DefaultColumns = []string{"id", "original_name", "size", "title"}
+
+// Layout describes the rows of a tabular export.
+type Layout struct {
+	// Columns are the system columns written before the view fields.
+	Columns []string `json:"columns"`
+	// FileRows writes a row for each file of an asset, rather than a row for each asset.
+	FileRows bool `json:"file_rows,omitempty"`
+	// Timezone is the IANA name of the timezone dates and date times are written in.
+	Timezone string `json:"timezone,omitempty"`
+	// FieldNames writes a second header row holding the name of each view field, so input
+	// matches the columns by name rather than by label.
+	FieldNames bool `json:"field_names,omitempty"`
+	// Long writes a row for each value of each field of an asset, rather than a row for each
+	// asset, ignoring the other options.
+	Long bool `json:"long,omitempty"`
+	// Collections writes the object type of each row of the long layout, for exports which
+	// include collections.
+	Collections bool `json:"collections,omitempty"`
+
+	// paths resolves the collection_path column.
+	paths *CollectionPaths
+}
+
+// WithCollectionPaths returns a copy of the layout which resolves the collection_path column
+// with paths.
+func (l Layout) WithCollectionPaths(paths *CollectionPaths) Layout {
+	l.paths = paths
+	return l
+}
+
+// CollectionPaths returns the collection paths the layout resolves, if any.
+func (l Layout) CollectionPaths() *CollectionPaths {
+	return l.paths
+}
+
+// HasColumn reports whether the layout writes the system column c.
+func (l Layout) HasColumn(c string) bool {
+	for _, col := range l.Columns {
+		if col == c {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionPath returns the collection_path column of obj, its collection titles separated
+// by slashes.
+func (l Layout) collectionPath(obj searchdomain.ObjectDTO) (string, error) {
+	if l.paths == nil || !l.HasColumn(CollectionPathColumn) {
+		return "", nil
+	}
+
+	path, err := l.paths.Path(obj)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(path, "/"), nil
+}
+
+// Location returns the layout's timezone, which defaults to UTC.
+func (l Layout) Location() *time.Location {
+	loc, err := time.LoadLocation(l.Timezone)
+	if err != nil || l.Timezone == "" {
+		return time.UTC
+	}
+	return loc
+}
+
+// systemColumn is an asset or file level column, written before the view fields. File level
+// columns are given the file of the row, which is nil for assets without files.
+type systemColumn struct {
+	typ       string
+	fileLevel bool
+	value     func(obj searchdomain.ObjectDTO, file *searchdomain.FileDTO) string
+}
+
+// asset returns the value of an asset level column.
+func asset(typ string, value func(obj searchdomain.ObjectDTO) string) systemColumn {
+	return systemColumn{typ: typ, value: func(obj searchdomain.ObjectDTO, _ *searchdomain.FileDTO) string {
+		return value(obj)
+	}}
+}
+
+// file returns the value of a file level column, or missing for assets without files.
+func file(typ, missing string, value func(f searchdomain.FileDTO) string) systemColumn {
+	return systemColumn{typ: typ, fileLevel: true, value: func(_ searchdomain.ObjectDTO, f *searchdomain.FileDTO) string {
+		if f == nil {
+			return missing
+		}
+		return value(*f)
+	}}
+}
+
+// systemColumns are the columns which can be chosen, keyed by the label written to the header.
+var systemColumns = map[string]systemColumn{
+	"id":            asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.ID }),
+	"original_name": file(xlsxio.TypeText, "N/A", func(f searchdomain.FileDTO) string { return f.OriginalName }),
+	"size":          file(xlsxio.TypeInteger, "N/A", func(f searchdomain.FileDTO) string { return strconv.Itoa(f.Size) }),
+	"title":         asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.Title }),
+	"date_created":  asset(xlsxio.TypeDateTime, func(obj searchdomain.ObjectDTO) string { return formatDateTime(obj.DateCreated) }),
+	"date_modified": asset(xlsxio.TypeDateTime, func(obj searchdomain.ObjectDTO) string { return formatDateTime(obj.DateModified) }),
+	"media_type":    asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.MediaType }),
+	"format":        asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.Format }),
+	"duration": asset(xlsxio.TypeInteger, func(obj searchdomain.ObjectDTO) string {
+		if obj.DurationMilliseconds == 0 {
+			return ""
+		}
+		return strconv.Itoa(obj.DurationMilliseconds)
+	}),
+	"archive_status":  asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.ArchiveStatus }),
+	"is_online":       asset(xlsxio.TypeBoolean, func(obj searchdomain.ObjectDTO) string { return strconv.FormatBool(obj.IsOnline) }),
+	"versions_number": asset(xlsxio.TypeInteger, func(obj searchdomain.ObjectDTO) string { return strconv.Itoa(obj.VersionsNumber) }),
+	"created_by_user": asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.CreatedByUser }),
+	"file_id":         file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.Id }),
+	"file_name":       file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.Name }),
+	"directory_path":  file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.DirectoryPath }),
+	"storage_id":      file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.StorageId }),
+	"storage_method":  file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.StorageMethod }),
+	"format_id":       file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.FormatId }),
+	"file_set_id":     file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.FileSetId }),
+	"file_status":     file(xlsxio.TypeText, "", func(f searchdomain.FileDTO) string { return f.Status }),
+	"files":           asset(xlsxio.TypeText, formatFiles),
+	"object_type":     asset(xlsxio.TypeText, func(obj searchdomain.ObjectDTO) string { return obj.ObjectType }),
+	// written by FormatResultsObjects from the layout's collection paths.
+	CollectionPathColumn: asset(xlsxio.TypeText, func(searchdomain.ObjectDTO) string { return "" }),
+}
+
+// fileEntry is a file as it is written to the files column.
+type fileEntry struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	OriginalName  string `json:"original_name"`
+	Size          int    `json:"size"`
+	DirectoryPath string `json:"directory_path"`
+	StorageID     string `json:"storage_id"`
+	FormatID      string `json:"format_id"`
+	FileSetID     string `json:"file_set_id"`
+	Status        string `json:"status"`
+}
+
+// formatFiles formats every file of an asset as a JSON array, so the list can be parsed again
+// whatever the file names contain.
+func formatFiles(obj searchdomain.ObjectDTO) string {
+	entries := make([]fileEntry, len(obj.Files))
+	for i, f := range obj.Files {
+		entries[i] = fileEntry{
+			ID:            f.Id,
+			Name:          f.Name,
+			OriginalName:  f.OriginalName,
+			Size:          f.Size,
+			DirectoryPath: f.DirectoryPath,
+			StorageID:     f.StorageId,
+			FormatID:      f.FormatId,
+			FileSetID:     f.FileSetId,
+			Status:        f.Status,
+		}
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// ParseColumns parses a comma separated list of system columns, in the order they are written.
+func ParseColumns(s string) ([]string, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, c := range strings.Split(s, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if _, ok := systemColumns[c]; !ok {
+			return nil, fmt.Errorf("unknown column %s, available columns are: %s", c, strings.Join(columnNames(), ", "))
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("column %s is listed more than once", c)
+		}
+		seen[c] = true
+		columns = append(columns, c)
+	}
+
+	return columns, nil
+}
+
+// columnNames returns the names of every system column, defaults first.
+func columnNames() []string {
+	return record.SystemColumns
+}
+
+// rowFiles returns the file of each row written for an object: every file with FileRows, or
+// otherwise the first. Objects without files have a single row with a nil file.
+func (l Layout) rowFiles(obj searchdomain.ObjectDTO) []*searchdomain.FileDTO {
+	if len(obj.Files) == 0 {
+		return []*searchdomain.FileDTO{nil}
+	}
+	if !l.FileRows {
+		return []*searchdomain.FileDTO{&obj.Files[0]}
+	}
+
+	files := make([]*searchdomain.FileDTO, len(obj.Files))
+	for i := range obj.Files {
+		files[i] = &obj.Files[i]
+	}
+	return files
+}
+
+func formatDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
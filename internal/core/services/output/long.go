@@ -0,0 +1,66 @@
+package output
+
+import (
+	"strconv"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+)
+
+// LongColumns are the columns of the long layout, which has a row for each value of each field
+// of an asset.
+var LongColumns = []string{"asset_id", "field_name", "field_label", "value", "value_index"}
+
+// longHeaders returns the header row of the long layout. The object type of each row is only
+// written when collections are exported too.
+func longHeaders(layout Layout) [][]string {
+	header := append([]string{}, LongColumns...)
+	if layout.Collections {
+		header = append(header, "object_type")
+	}
+	return [][]string{header}
+}
+
+// longColumns describes the columns written by longHeaders.
+func longColumns(layout Layout) []xlsxio.Column {
+	var columns []xlsxio.Column
+	for _, c := range longHeaders(layout)[0] {
+		typ := xlsxio.TypeText
+		if c == "value_index" {
+			typ = xlsxio.TypeInteger
+		}
+		columns = append(columns, xlsxio.Column{Label: c, Type: typ})
+	}
+	return columns
+}
+
+// longRows formats the results of a search in the long layout, writing a row for each value of
+// each view field. Fields without values, and empty values, have no rows.
+func longRows(layout Layout, viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) [][]string {
+	types := fieldTypes(viewFields)
+	loc := layout.Location()
+
+	var rows [][]string
+	for _, object := range objs {
+		for _, field := range viewFields {
+			if field.Name == "__separator__" {
+				continue
+			}
+
+			for index, elem := range object.Metadata[field.Name] {
+				value := formatValue(elem, types[field.Name], loc)
+				if value == "" {
+					continue
+				}
+				row := []string{object.ID, field.Name, field.Label, value, strconv.Itoa(index)}
+				if layout.Collections {
+					row = append(row, object.ObjectType)
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	return rows
+}
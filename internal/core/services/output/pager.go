@@ -0,0 +1,126 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// page is a page of search results, or the error which ended the search.
+type page struct {
+	objs   []searchdomain.ObjectDTO
+	facets map[string]searchdomain.FacetDTO
+	err    error
+}
+
+// Pager iterates over the pages of a search using search_after pagination. The next page
+// is fetched in the background while the current one is being written.
+type Pager struct {
+	ctx    context.Context
+	pages  chan page
+	cancel context.CancelFunc
+	objs   []searchdomain.ObjectDTO
+	facets map[string]searchdomain.FacetDTO
+	err    error
+}
+
+// NewPager returns a new Pager which runs the search s, starting after the searchAfter sort
+// values if any are given. The Pager must be closed once it is no longer needed.
+func (svc *Svc) NewPager(ctx context.Context, s searchdomain.Search, searchAfter []interface{}) *Pager {
+	ctx, cancel := context.WithCancel(ctx)
+
+	p := &Pager{
+		ctx: ctx,
+		// a buffer of one page lets the next page be fetched while the current one is written.
+		pages:  make(chan page, 1),
+		cancel: cancel,
+	}
+
+	go svc.fetch(ctx, s, searchAfter, p.pages)
+
+	return p
+}
+
+// fetch sends each page of the search to pages until the results run out, an error
+// occurs or ctx is cancelled.
+func (svc *Svc) fetch(ctx context.Context, s searchdomain.Search, searchAfter []interface{}, pages chan<- page) {
+	defer close(pages)
+
+	for {
+		s.SearchAfter = []interface{}{}
+		if len(searchAfter) > 0 {
+			s.SearchAfter = searchAfter
+		}
+
+		results, err := svc.searchPage(ctx, s)
+		objs := results.Objects
+		if err == nil && len(objs) == 0 {
+			return
+		}
+
+		select {
+		case pages <- page{objs: objs, facets: results.Facets, err: err}:
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		searchAfter = objs[len(objs)-1].Sort
+		if len(searchAfter) == 0 {
+			// without sort values there is no way to ask for the next page.
+			return
+		}
+	}
+}
+
+func (svc *Svc) searchPage(ctx context.Context, s searchdomain.Search) (searchdomain.ResultsDTO, error) {
+	sPayload, err := json.Marshal(s)
+	if err != nil {
+		return searchdomain.ResultsDTO{}, err
+	}
+
+	return svc.searchSvc.Search(ctx, iconik.SearchPath, sPayload)
+}
+
+// Next waits for the next page, returning false when there are no more pages, the search
+// failed or the context was cancelled. The page is available from Objects.
+func (p *Pager) Next() bool {
+	pg, ok := <-p.pages
+	switch {
+	case !ok:
+		p.objs, p.facets, p.err = nil, nil, p.ctx.Err()
+		return false
+	case pg.err != nil:
+		p.objs, p.facets, p.err = nil, nil, pg.err
+		return false
+	}
+
+	p.objs, p.facets = pg.objs, pg.facets
+	return true
+}
+
+// Objects returns the objects of the current page.
+func (p *Pager) Objects() []searchdomain.ObjectDTO {
+	return p.objs
+}
+
+// Facets returns the facets of the search, counted across every page of its results, as they
+// were returned with the current page.
+func (p *Pager) Facets() map[string]searchdomain.FacetDTO {
+	return p.facets
+}
+
+// Err returns the error which ended the search, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// Close stops fetching pages.
+func (p *Pager) Close() {
+	p.cancel()
+}
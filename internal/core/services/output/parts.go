@@ -0,0 +1,313 @@
+package output
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+const (
+	// CompressGzip compresses each part of an export into its own .gz file.
+	CompressGzip = "gzip"
+	// CompressZip writes every part of an export into a single .zip archive.
+	CompressZip = "zip"
+
+	// ManifestExt is appended to the path of an export to name its manifest.
+	ManifestExt = ".manifest.json"
+)
+
+// Manifest lists the parts an export was written to, so they can be checked once delivered.
+type Manifest struct {
+	Format      string `json:"format"`
+	Compression string `json:"compression,omitempty"`
+	// Archive is the name of the zip archive holding the parts, if they are zipped.
+	Archive string `json:"archive,omitempty"`
+	// Rows is the number of rows or records written to every part, not counting headers.
+	Rows  int    `json:"rows"`
+	Parts []Part `json:"parts"`
+}
+
+// Part is a single file of an export. Its size and SHA-256 checksum are those of the file as
+// it is stored, or of the entry's contents for the parts of a zip archive.
+type Part struct {
+	Name   string `json:"name"`
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// Files creates the files an export is written to, either as they are, gzip compressed, or as
+// the entries of a single zip archive, and keeps the Manifest of the parts created.
+type Files struct {
+	base     string
+	format   string
+	numbered bool
+	manifest Manifest
+	archive  *os.File
+	zw       *zip.Writer
+}
+
+// NewFiles returns a new Files which names its parts after the base path, with the extension
+// of format. The parts are numbered if numbered is set, and compressed as compression, which
+// is either CompressGzip, CompressZip or empty.
+func NewFiles(base, format, compression string, numbered bool) (*Files, error) {
+	fs := &Files{
+		base:     base,
+		format:   format,
+		numbered: numbered,
+		manifest: Manifest{Format: format, Compression: compression, Parts: []Part{}},
+	}
+
+	if compression == CompressZip {
+		f, err := os.Create(base + ".zip")
+		if err != nil {
+			return nil, err
+		}
+		fs.archive, fs.zw = f, zip.NewWriter(f)
+		fs.manifest.Archive = filepath.Base(f.Name())
+	}
+
+	return fs, nil
+}
+
+// Create creates the nth part, counting from 1.
+func (fs *Files) Create(n int) (*PartFile, error) {
+	name := fs.base
+	if fs.numbered {
+		name += fmt.Sprintf("_part%03d", n)
+	}
+	name += "." + fs.format
+
+	pf := &PartFile{files: fs, sum: sha256.New()}
+
+	switch {
+	case fs.zw != nil:
+		pf.part.Name = filepath.Base(name)
+		entry, err := fs.zw.Create(pf.part.Name)
+		if err != nil {
+			return nil, err
+		}
+		pf.w = io.MultiWriter(entry, pf.sum)
+	case fs.manifest.Compression == CompressGzip:
+		name += ".gz"
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		pf.part.Name, pf.f = filepath.Base(name), f
+		pf.gz = gzip.NewWriter(io.MultiWriter(f, pf.sum))
+		pf.w = pf.gz
+	default:
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		pf.part.Name, pf.f = filepath.Base(name), f
+		pf.w = io.MultiWriter(f, pf.sum)
+	}
+
+	return pf, nil
+}
+
+// Manifest returns the manifest of the parts closed so far.
+func (fs *Files) Manifest() Manifest {
+	return fs.manifest
+}
+
+// Close closes the zip archive, if there is one, and writes the manifest next to the parts.
+func (fs *Files) Close() error {
+	if fs.zw != nil {
+		err := fs.zw.Close()
+		if cerr := fs.archive.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := json.MarshalIndent(fs.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fs.base+ManifestExt, append(b, '\n'), 0644)
+}
+
+// PartFile is a part of an export being written. It counts the bytes written to it before any
+// compression, so an export can be split by size.
+type PartFile struct {
+	files   *Files
+	part    Part
+	w       io.Writer
+	f       *os.File
+	gz      *gzip.Writer
+	sum     hash.Hash
+	written int64
+}
+
+// Write writes p to the part.
+func (pf *PartFile) Write(p []byte) (int, error) {
+	n, err := pf.w.Write(p)
+	pf.written += int64(n)
+	return n, err
+}
+
+// Size returns the number of bytes written to the part, before any compression.
+func (pf *PartFile) Size() int64 {
+	return pf.written
+}
+
+// Close closes the part, adding it to the manifest with its number of rows.
+func (pf *PartFile) Close(rows int) error {
+	if pf.gz != nil {
+		if err := pf.gz.Close(); err != nil {
+			pf.f.Close()
+			return err
+		}
+	}
+
+	pf.part.Bytes = pf.written
+	if pf.f != nil {
+		info, err := pf.f.Stat()
+		if err != nil {
+			pf.f.Close()
+			return err
+		}
+		pf.part.Bytes = info.Size()
+
+		if err = pf.f.Close(); err != nil {
+			return err
+		}
+	}
+
+	pf.part.Rows = rows
+	pf.part.SHA256 = hex.EncodeToString(pf.sum.Sum(nil))
+	pf.files.manifest.Parts = append(pf.files.manifest.Parts, pf.part)
+	pf.files.manifest.Rows += rows
+
+	return nil
+}
+
+// SplitWriter writes an export to a series of parts, rolling over to a new part once the
+// current one holds a number of rows or bytes. Each part is written by its own Writer, so
+// it has its own header. Objects are never split across parts, so a part can run over the
+// limits by the rows of a single object.
+type SplitWriter struct {
+	files      *Files
+	newWriter  func(f io.Writer) (Writer, error)
+	maxRows    int
+	maxBytes   int64
+	viewFields []metadatadomain.ViewFieldDTO
+	n          int
+	w          Writer
+	part       *PartFile
+}
+
+// NewSplitWriter returns a new SplitWriter which creates its parts with files, and writes to
+// each with a Writer returned by newWriter. A zero limit is not applied, so with neither
+// limit the whole export is written to a single part.
+func (svc *Svc) NewSplitWriter(files *Files, newWriter func(f io.Writer) (Writer, error), maxRows int, maxBytes int64) *SplitWriter {
+	return &SplitWriter{
+		files:     files,
+		newWriter: newWriter,
+		maxRows:   maxRows,
+		maxBytes:  maxBytes,
+	}
+}
+
+// WriteHeader creates the first part, so an export without results still has a file.
+func (sw *SplitWriter) WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error {
+	sw.viewFields = viewFields
+	return sw.open()
+}
+
+// WriteObjects writes the objects to the current part, rolling over to a new part whenever
+// the limits are reached.
+func (sw *SplitWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	if sw.maxRows == 0 && sw.maxBytes == 0 {
+		return sw.w.WriteObjects(viewFields, objs)
+	}
+
+	for i := range objs {
+		if sw.w == nil {
+			if err := sw.open(); err != nil {
+				return err
+			}
+		}
+
+		if err := sw.w.WriteObjects(viewFields, objs[i:i+1]); err != nil {
+			return err
+		}
+
+		if (sw.maxRows > 0 && sw.rows() >= sw.maxRows) || (sw.maxBytes > 0 && sw.part.Size() >= sw.maxBytes) {
+			if err := sw.close(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Manifest returns the manifest of the parts written.
+func (sw *SplitWriter) Manifest() Manifest {
+	return sw.files.Manifest()
+}
+
+// Close closes the current part and the files.
+func (sw *SplitWriter) Close() error {
+	if sw.w != nil {
+		if err := sw.close(); err != nil {
+			return err
+		}
+	}
+	return sw.files.Close()
+}
+
+func (sw *SplitWriter) open() error {
+	sw.n++
+	part, err := sw.files.Create(sw.n)
+	if err != nil {
+		return err
+	}
+
+	w, err := sw.newWriter(part)
+	if err != nil {
+		return err
+	}
+	if err = w.WriteHeader(sw.viewFields); err != nil {
+		return err
+	}
+
+	sw.w, sw.part = w, part
+	return nil
+}
+
+func (sw *SplitWriter) close() error {
+	rows := sw.rows()
+	if err := sw.w.Close(); err != nil {
+		return err
+	}
+	sw.w = nil
+
+	return sw.part.Close(rows)
+}
+
+// rows returns the number of rows written to the current part.
+func (sw *SplitWriter) rows() int {
+	if c, ok := sw.w.(RowCounter); ok {
+		return c.Rows()
+	}
+	return 0
+}
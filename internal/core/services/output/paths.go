@@ -0,0 +1,285 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	colldomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/collections"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// CollectionPaths resolves the subcollection each asset lives in, as the titles of the
+// collections from the searched collection down to the asset's own. Every collection is
+// fetched once, and the path of each is cached.
+type CollectionPaths struct {
+	ctx   context.Context
+	svc   *Svc
+	roots map[string]bool
+	colls map[string]colldomain.CollectionDTO
+	paths map[string][]string
+}
+
+// NewCollectionPaths returns a new CollectionPaths for a search of the roots collections. With
+// no roots, paths run from the top level collection.
+func (svc *Svc) NewCollectionPaths(ctx context.Context, roots []string) *CollectionPaths {
+	p := &CollectionPaths{
+		ctx:   ctx,
+		svc:   svc,
+		roots: make(map[string]bool, len(roots)),
+		colls: make(map[string]colldomain.CollectionDTO),
+		paths: make(map[string][]string),
+	}
+	for _, id := range roots {
+		p.roots[id] = true
+	}
+
+	return p
+}
+
+// Path returns the collection path of obj. An asset in several collections takes the first one
+// within the searched collections, and an asset in none has an empty path.
+func (p *CollectionPaths) Path(obj searchdomain.ObjectDTO) ([]string, error) {
+	for _, id := range obj.InCollections {
+		path, err := p.collectionPath(id)
+		if err != nil {
+			return nil, err
+		}
+		if path != nil {
+			return path, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// collectionPath returns the path of the collection id, or nil if it is outside the searched
+// collections.
+func (p *CollectionPaths) collectionPath(id string) ([]string, error) {
+	if path, ok := p.paths[id]; ok {
+		return path, nil
+	}
+
+	var path []string
+	seen := make(map[string]bool)
+	for next := id; next != "" && !seen[next]; {
+		seen[next] = true
+
+		coll, err := p.collection(next)
+		if err != nil {
+			return nil, err
+		}
+		path = append([]string{coll.Title}, path...)
+
+		if p.roots[next] {
+			p.paths[id] = path
+			return path, nil
+		}
+		next = coll.ParentID
+	}
+
+	// the top of the tree was reached without passing a searched collection.
+	if len(p.roots) > 0 {
+		path = nil
+	}
+	p.paths[id] = path
+
+	return path, nil
+}
+
+func (p *CollectionPaths) collection(id string) (colldomain.CollectionDTO, error) {
+	if coll, ok := p.colls[id]; ok {
+		return coll, nil
+	}
+
+	coll, err := p.svc.GetCollection(p.ctx, id)
+	if err != nil {
+		return colldomain.CollectionDTO{}, err
+	}
+	p.colls[id] = coll
+
+	return coll, nil
+}
+
+// TreeWriter splits an export into a file for each collection path, so every subcollection has
+// its own file. Paths are grouped by the name of their file, so titles which only differ in
+// characters a file name can't hold share a file rather than overwrite each other.
+//
+// Only one file is open at a time, however many subcollections there are. Appendable formats are
+// reopened for each page of assets and appended to without a header. Other formats are spooled
+// to a temporary folder, and each file is written in turn on Close.
+type TreeWriter struct {
+	paths      *CollectionPaths
+	name       func(path []string) string
+	open       func(name string, appending bool) (Writer, error)
+	appendable bool
+	viewFields []metadatadomain.ViewFieldDTO
+	// files numbers the files written to so far, in the order they were first written.
+	files map[string]int
+	order []string
+	spool string
+}
+
+// NewTreeWriter returns a new TreeWriter which names the file of each path with name, and opens
+// its Writer with open. Appendable formats are opened for each page they are written to, and
+// appending is set on every open after the first.
+func (svc *Svc) NewTreeWriter(paths *CollectionPaths, name func(path []string) string, open func(name string, appending bool) (Writer, error), appendable bool) *TreeWriter {
+	return &TreeWriter{
+		paths:      paths,
+		name:       name,
+		open:       open,
+		appendable: appendable,
+		files:      make(map[string]int),
+	}
+}
+
+// WriteHeader keeps the view fields, so a header can be written to each file as it is created.
+func (tw *TreeWriter) WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error {
+	tw.viewFields = viewFields
+	return nil
+}
+
+// WriteObjects writes each object to the file of its collection path.
+func (tw *TreeWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	var names []string
+	groups := make(map[string][]searchdomain.ObjectDTO)
+
+	for _, obj := range objs {
+		path, err := tw.paths.Path(obj)
+		if err != nil {
+			return err
+		}
+
+		name := tw.name(path)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], obj)
+	}
+
+	for _, name := range names {
+		_, appending := tw.files[name]
+		if !appending {
+			tw.files[name] = len(tw.order)
+			tw.order = append(tw.order, name)
+		}
+
+		var err error
+		if tw.appendable {
+			err = tw.write(name, appending, viewFields, groups[name])
+		} else {
+			err = tw.spoolObjects(name, groups[name])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write opens the Writer of the file name, writes objs to it and closes it again. The header is
+// written when the file is first opened.
+func (tw *TreeWriter) write(name string, appending bool, viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	w, err := tw.open(name, appending)
+	if err != nil {
+		return err
+	}
+
+	if !appending {
+		if err = w.WriteHeader(tw.viewFields); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err = w.WriteObjects(viewFields, objs); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// spoolObjects appends objs to the spool of the file name, as a line of JSON.
+func (tw *TreeWriter) spoolObjects(name string, objs []searchdomain.ObjectDTO) error {
+	if tw.spool == "" {
+		dir, err := os.MkdirTemp("", "iconik-io-tree-")
+		if err != nil {
+			return err
+		}
+		tw.spool = dir
+	}
+
+	f, err := os.OpenFile(tw.spoolPath(name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(f).Encode(objs); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// spoolPath returns the path of the spool of the file name, numbered by the order it was first
+// written in.
+func (tw *TreeWriter) spoolPath(name string) string {
+	return filepath.Join(tw.spool, strconv.Itoa(tw.files[name])+".json")
+}
+
+// Close writes each spooled file in turn, then removes the spools. Every file is written even if
+// one fails, and the first error is returned.
+func (tw *TreeWriter) Close() error {
+	if tw.spool == "" {
+		return nil
+	}
+	defer os.RemoveAll(tw.spool)
+
+	var first error
+	for _, name := range tw.order {
+		if err := tw.unspool(name); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// unspool writes the objects spooled for the file name to its Writer, a page at a time.
+func (tw *TreeWriter) unspool(name string) error {
+	f, err := os.Open(tw.spoolPath(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := tw.open(name, false)
+	if err != nil {
+		return err
+	}
+	if err = w.WriteHeader(tw.viewFields); err != nil {
+		w.Close()
+		return err
+	}
+
+	dec := json.NewDecoder(f)
+	for {
+		var objs []searchdomain.ObjectDTO
+		if err = dec.Decode(&objs); err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = w.WriteObjects(tw.viewFields, objs)
+		}
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	return w.Close()
+}
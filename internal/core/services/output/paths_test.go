@@ -0,0 +1,117 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	colldomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/collections"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// fakeFiles records what is written to each file by the writers of a TreeWriter.
+type fakeFiles struct {
+	files map[string][]string
+	open  int
+}
+
+// fakeWriter writes the header and the IDs of objects as lines of a fakeFiles file.
+type fakeWriter struct {
+	ff   *fakeFiles
+	name string
+}
+
+// opener returns the open function of a TreeWriter, which checks files are created before they
+// are appended to, and opened one at a time.
+func (ff *fakeFiles) opener(t *testing.T) func(name string, appending bool) (Writer, error) {
+	return func(name string, appending bool) (Writer, error) {
+		if _, ok := ff.files[name]; ok != appending {
+			t.Errorf("open(%s, appending %v) of a file created before %v", name, appending, ok)
+		}
+		if !appending {
+			ff.files[name] = nil
+		}
+		if ff.open++; ff.open > 1 {
+			t.Errorf("open(%s) while %d files are open", name, ff.open-1)
+		}
+		return &fakeWriter{ff: ff, name: name}, nil
+	}
+}
+
+func (fw *fakeWriter) WriteHeader(_ []metadatadomain.ViewFieldDTO) error {
+	fw.ff.files[fw.name] = append(fw.ff.files[fw.name], "header")
+	return nil
+}
+
+func (fw *fakeWriter) WriteObjects(_ []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	for _, obj := range objs {
+		fw.ff.files[fw.name] = append(fw.ff.files[fw.name], obj.ID)
+	}
+	return nil
+}
+
+func (fw *fakeWriter) Close() error {
+	fw.ff.open--
+	return nil
+}
+
+func TestTreeWriter(t *testing.T) {
+	svc := &Svc{}
+	paths := svc.NewCollectionPaths(context.Background(), []string{"root"})
+	for id, title := range map[string]string{"ab": "A/B", "a_b": "A_B", "c": "C"} {
+		paths.colls[id] = colldomain.CollectionDTO{ID: id, Title: title, ParentID: "root"}
+	}
+	paths.colls["root"] = colldomain.CollectionDTO{ID: "root", Title: "Root"}
+	paths.colls["outside"] = colldomain.CollectionDTO{ID: "outside", Title: "Outside"}
+
+	// files are named as the output app names them, so the titles A/B and A_B share a file.
+	name := func(path []string) string {
+		return strings.ReplaceAll(strings.Join(path, "|"), "/", "_")
+	}
+	obj := func(id, coll string) searchdomain.ObjectDTO {
+		return searchdomain.ObjectDTO{ID: id, InCollections: []string{coll}}
+	}
+	pages := [][]searchdomain.ObjectDTO{
+		{obj("1", "ab"), obj("2", "c"), obj("3", "a_b")},
+		{obj("4", "a_b"), obj("5", "ab"), obj("6", "outside")},
+	}
+
+	// assets outside the searched collection have an empty path.
+	want := map[string][]string{
+		"Root|A_B": {"header", "1", "3", "4", "5"},
+		"Root|C":   {"header", "2"},
+		"":         {"header", "6"},
+	}
+
+	for _, appendable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("appendable %v", appendable), func(t *testing.T) {
+			ff := &fakeFiles{files: make(map[string][]string)}
+			tw := svc.NewTreeWriter(paths, name, ff.opener(t), appendable)
+
+			if err := tw.WriteHeader(nil); err != nil {
+				t.Fatal(err)
+			}
+			for _, page := range pages {
+				if err := tw.WriteObjects(nil, page); err != nil {
+					t.Fatal(err)
+				}
+				if ff.open != 0 {
+					t.Errorf("%d files left open after a page", ff.open)
+				}
+			}
+			if !appendable && len(ff.files) != 0 {
+				t.Errorf("files written before Close: %v", ff.files)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ff.files, want) {
+				t.Errorf("files = %v, want %v", ff.files, want)
+			}
+		})
+	}
+}
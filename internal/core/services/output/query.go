@@ -0,0 +1,245 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/config"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+)
+
+// Query selects the assets an export includes.
+type Query struct {
+	// ObjectTypes are the types of object exported, assets, collections or both. No types
+	// exports assets.
+	ObjectTypes []string `json:"object_types,omitempty"`
+	// CollectionIDs limits the export to assets within any of the collections, or their
+	// subcollections. No collections searches every asset.
+	CollectionIDs []string `json:"collection_ids,omitempty"`
+	// Text is a free text query.
+	Text string `json:"text,omitempty"`
+	// Terms are extra filter terms every asset must match.
+	Terms []searchdomain.Term `json:"terms,omitempty"`
+	// ModifiedSince limits the export to assets modified after it, unless it is zero. Deleted
+	// assets are limited to those deleted after it.
+	ModifiedSince time.Time `json:"modified_since,omitempty"`
+	// Deleted exports the assets deleted since ModifiedSince, rather than active assets.
+	Deleted bool `json:"deleted,omitempty"`
+	// Conditions are matched against the search results, for conditions the search can't
+	// express as filter terms.
+	Conditions []Condition `json:"conditions,omitempty"`
+	// Sort orders the assets, newest first if it is empty.
+	Sort []searchdomain.Sort `json:"sort,omitempty"`
+}
+
+// NewQuery returns the search selected by the collection, search, filter and sort flags.
+func NewQuery(cfg *config.App) (Query, error) {
+	q := Query{ObjectTypes: cfg.ObjectTypes(), CollectionIDs: cfg.CollectionIDs(), Text: cfg.Query, Deleted: cfg.Deleted}
+	for _, f := range cfg.Filters {
+		term, err := ParseTerm(f)
+		if err != nil {
+			return Query{}, err
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	for _, w := range cfg.Where {
+		c, err := ParseCondition(w)
+		if err != nil {
+			return Query{}, err
+		}
+		q.Where(c)
+	}
+	if cfg.Sort != "" {
+		sort, err := ParseSort(cfg.Sort)
+		if err != nil {
+			return Query{}, err
+		}
+		q.Sort = sort
+	}
+
+	return q, nil
+}
+
+// Search returns the search for the assets selected by q, exported with viewFields. The
+// conditions of q the search can narrow the results to are added as filter terms.
+func (svc *Svc) Search(q Query, viewFields []metadatadomain.ViewFieldDTO) searchdomain.Search {
+	status := "ACTIVE"
+	if q.Deleted {
+		status = "DELETED"
+	}
+
+	s := searchdomain.Search{
+		DocTypes:      []string{"assets", "collections"},
+		Facets:        []string{"object_type", "media_type", "archive_status", "type", "format", "is_online", "approval_status"},
+		IncludeFields: []string{"id", "title", "files", "in_collections", "metadata", "files.size", "media_type", "format", "duration_milliseconds", "date_created", "date_modified", "object_type", "archive_status", "is_online", "versions_number", "created_by_user"},
+		Sort:          q.sort(),
+		Query:         q.Text,
+		Filter: searchdomain.Filter{
+			Operator: "AND",
+			Terms: []searchdomain.Term{
+				{Name: "status", ValueIn: []string{status}},
+			},
+		},
+		FacetsFilters: []searchdomain.FacetsFilter{
+			{Name: "object_type", ValueIn: q.objectTypes()},
+		},
+		SearchFields: []string{"title", "description", "segment_text", "file_names", "metadata", "transcription_text"},
+		SearchAfter:  []interface{}{},
+	}
+
+	if len(q.CollectionIDs) > 0 {
+		s.Filter.Terms = append([]searchdomain.Term{{Name: "ancestor_collections", ValueIn: q.CollectionIDs}}, s.Filter.Terms...)
+	}
+	s.Filter.Terms = append(s.Filter.Terms, q.Terms...)
+	terms, _ := q.conditionTerms(fieldTypes(viewFields))
+	s.Filter.Terms = append(s.Filter.Terms, terms...)
+
+	if !q.ModifiedSince.IsZero() {
+		// deletions are tracked by the time they were made, rather than date_modified.
+		field := "date_modified"
+		if q.Deleted {
+			field = "date_deleted"
+		}
+		s.Filter.Terms = append(s.Filter.Terms, searchdomain.Term{
+			Name:  field,
+			Range: &searchdomain.Range{Min: q.ModifiedSince.UTC().Format(time.RFC3339)},
+		})
+	}
+
+	return s
+}
+
+// sort returns the sort order of the search, which is newest first unless one is chosen.
+// Assets sharing the sort values are ordered by ID, so search_after pagination, and resuming
+// from a saved cursor, neither skips nor repeats any of them.
+func (q Query) sort() []searchdomain.Sort {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = []searchdomain.Sort{{Name: "date_created", Order: "desc"}}
+	}
+
+	if !slices.ContainsFunc(sort, func(s searchdomain.Sort) bool { return s.Name == "id" }) {
+		sort = append(slices.Clip(sort), searchdomain.Sort{Name: "id", Order: "asc"})
+	}
+	return sort
+}
+
+// objectTypes returns the types of object exported, which default to assets.
+func (q Query) objectTypes() []string {
+	if len(q.ObjectTypes) == 0 {
+		return []string{record.ObjectTypeAssets}
+	}
+	return q.ObjectTypes
+}
+
+// Key identifies the assets selected by the query, regardless of when they were modified
+// or whether they were deleted. It names the last run of incremental exports of the query.
+func (q Query) Key() string {
+	key := "all"
+	if len(q.CollectionIDs) > 0 {
+		key = strings.Join(q.CollectionIDs, "+")
+	}
+	if types := q.objectTypes(); len(types) != 1 || types[0] != record.ObjectTypeAssets {
+		key += "_" + strings.Join(types, "+")
+	}
+
+	if q.Text == "" && len(q.Terms) == 0 && len(q.Conditions) == 0 {
+		return key
+	}
+
+	h := fnv.New32a()
+	b, _ := json.Marshal(q.Terms)
+	h.Write([]byte(q.Text))
+	h.Write(b)
+	if len(q.Conditions) > 0 {
+		b, _ = json.Marshal(q.Conditions)
+		h.Write(b)
+	}
+
+	return fmt.Sprintf("%s_%08x", key, h.Sum32())
+}
+
+// ParseTerm parses a filter term from name=value, where value is either a comma separated list
+// of values or a range written min..max. Either end of a range may be left out.
+func ParseTerm(s string) (searchdomain.Term, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || value == "" {
+		return searchdomain.Term{}, fmt.Errorf("invalid filter %s, expected name=value, name=value1,value2 or name=min..max", s)
+	}
+
+	if min, max, ok := strings.Cut(value, ".."); ok {
+		return searchdomain.Term{
+			Name:  name,
+			Range: &searchdomain.Range{Min: strings.TrimSpace(min), Max: strings.TrimSpace(max)},
+		}, nil
+	}
+
+	values := strings.Split(value, ",")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+
+	return searchdomain.Term{Name: name, ValueIn: values}, nil
+}
+
+// searchFields are the search fields the asset level system columns are read from. The other
+// system columns, such as those of files, can't be searched or sorted on.
+var searchFields = map[string]string{
+	"id":              "id",
+	"title":           "title",
+	"date_created":    "date_created",
+	"date_modified":   "date_modified",
+	"media_type":      "media_type",
+	"format":          "format",
+	"duration":        "duration_milliseconds",
+	"archive_status":  "archive_status",
+	"is_online":       "is_online",
+	"versions_number": "versions_number",
+	"created_by_user": "created_by_user",
+	"object_type":     "object_type",
+}
+
+// searchField returns the search field of a system column or metadata field name, and whether
+// the search can filter and sort on it. Metadata fields are searched by their own name.
+func searchField(name string) (string, bool) {
+	if field, ok := searchFields[name]; ok {
+		return field, true
+	}
+	if _, ok := systemColumns[name]; ok {
+		return "", false
+	}
+	return name, true
+}
+
+// ParseSort parses a sort order from a comma separated list of name or name:order, where order
+// is asc or desc and defaults to asc. Names are search fields, such as the asset level system
+// columns or a metadata field name.
+func ParseSort(s string) ([]searchdomain.Sort, error) {
+	var sort []searchdomain.Sort
+	for _, field := range strings.Split(s, ",") {
+		name, order, _ := strings.Cut(strings.TrimSpace(field), ":")
+		name, order = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(order))
+		if order == "" {
+			order = "asc"
+		}
+
+		if name == "" || (order != "asc" && order != "desc") {
+			return nil, fmt.Errorf("invalid sort %s, expected name, name:asc or name:desc", field)
+		}
+		searchName, ok := searchField(name)
+		if !ok {
+			return nil, fmt.Errorf("can't sort by %s, only by asset level columns and metadata fields", name)
+		}
+
+		sort = append(sort, searchdomain.Sort{Name: searchName, Order: order})
+	}
+
+	return sort, nil
+}
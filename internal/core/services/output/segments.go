@@ -0,0 +1,145 @@
+package output
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/segments"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/timecode"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+)
+
+// SegmentWriter writes a row for each time based segment of the assets in the search results,
+// rather than a row for each asset. Segments are fetched for each asset as it is written.
+type SegmentWriter struct {
+	ctx    context.Context
+	svc    *Svc
+	w      RowWriter
+	layout Layout
+	types  []string
+	fps    float64
+	rows   int
+}
+
+// NewSegmentWriter returns a new SegmentWriter which writes the segments of the given types, or
+// of every type if none are given, to w. Times are written in milliseconds, or as timecodes at
+// fps frames per second if fps is not zero. The layout's system columns are replaced by the
+// record.SegmentColumns. If w is an io.Closer, it is closed when the SegmentWriter is closed.
+func (svc *Svc) NewSegmentWriter(ctx context.Context, w RowWriter, layout Layout, types []string, fps float64) *SegmentWriter {
+	layout.Columns, layout.FileRows, layout.Long = record.SegmentColumns, false, false
+
+	return &SegmentWriter{
+		ctx:    ctx,
+		svc:    svc,
+		w:      w,
+		layout: layout,
+		types:  types,
+		fps:    fps,
+	}
+}
+
+// SegmentColumns describes the type and drop-down options of each column of a segment export,
+// for output formats which support typed cells.
+func (svc *Svc) SegmentColumns(layout Layout, viewFields []metadatadomain.ViewFieldDTO, fps float64) []xlsxio.Column {
+	timeType := xlsxio.TypeInteger
+	if fps != 0 {
+		timeType = xlsxio.TypeText
+	}
+
+	columns := make([]xlsxio.Column, 0, len(record.SegmentColumns)+len(viewFields))
+	for _, c := range record.SegmentColumns {
+		col := xlsxio.Column{Label: c, Type: xlsxio.TypeText}
+		if c == "time_start" || c == "time_end" {
+			col.Type = timeType
+		}
+		columns = append(columns, col)
+	}
+
+	layout.Columns, layout.Long = nil, false
+	return append(columns, svc.Columns(layout, viewFields)...)
+}
+
+// WriteHeader writes the header row, followed by a row of field names if the layout has them.
+func (sw *SegmentWriter) WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error {
+	return sw.w.WriteAll(sw.svc.Headers(sw.layout, viewFields))
+}
+
+// WriteObjects writes a row for each segment of each asset. Collections have no segments.
+func (sw *SegmentWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	var rows [][]string
+	for _, object := range objs {
+		if object.ObjectType == record.ObjectTypeCollections {
+			continue
+		}
+
+		segs, err := sw.svc.segmentSvc.GetSegments(sw.ctx, object.ID, sw.types)
+		if err != nil {
+			return err
+		}
+
+		for _, seg := range segs {
+			row, err := sw.row(viewFields, seg)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+	}
+	sw.rows += len(rows)
+
+	return sw.w.WriteAll(rows)
+}
+
+// row formats a segment as a row of the record.SegmentColumns followed by the view fields.
+func (sw *SegmentWriter) row(viewFields []metadatadomain.ViewFieldDTO, seg segments.SegmentDTO) ([]string, error) {
+	start, err := sw.time(seg.TimeStartMilliseconds)
+	if err != nil {
+		return nil, err
+	}
+	end, err := sw.time(seg.TimeEndMilliseconds)
+	if err != nil {
+		return nil, err
+	}
+	row := []string{seg.AssetID, seg.ID, seg.SegmentType, start, end, seg.SegmentText}
+
+	types := fieldTypes(viewFields)
+	loc := sw.layout.Location()
+	for _, field := range viewFields {
+		if field.Name == "__separator__" {
+			continue
+		}
+
+		values := seg.Metadata[field.Name]
+		result := make([]string, len(values))
+		for i, elem := range values {
+			result[i] = formatValue(elem, types[field.Name], loc)
+		}
+		row = append(row, record.JoinValues(result))
+	}
+
+	return row, nil
+}
+
+func (sw *SegmentWriter) time(ms int64) (string, error) {
+	if sw.fps == 0 {
+		return strconv.FormatInt(ms, 10), nil
+	}
+	return timecode.Format(ms, sw.fps)
+}
+
+// Rows returns the number of segments written.
+func (sw *SegmentWriter) Rows() int {
+	return sw.rows
+}
+
+// Close closes the underlying RowWriter if it needs closing.
+func (sw *SegmentWriter) Close() error {
+	if c, ok := sw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
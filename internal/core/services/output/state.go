@@ -0,0 +1,142 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// StateExt is appended to the path of an output file to name its state file.
+const StateExt = ".state.json"
+
+// State is the progress of an export, saved after each page is written so a failed export
+// can be resumed from the last page rather than from the start.
+type State struct {
+	Query  Query  `json:"query"`
+	ViewID string `json:"view_id"`
+	Format string `json:"format"`
+	// Layout is the layout of tabular exports.
+	Layout Layout `json:"layout"`
+	// Started is when the export started, saved as the last run of an incremental export.
+	Started time.Time `json:"started"`
+	// Incremental is true for exports which save their last run once they complete.
+	Incremental bool `json:"incremental,omitempty"`
+	// SearchAfter is the _sort cursor of the last object written.
+	SearchAfter []interface{} `json:"search_after"`
+	// Rows is the number of assets written.
+	Rows int `json:"rows"`
+	// Offset is the size of the output file once the last page was written. Anything after
+	// it is a partly written page, which is truncated when resuming.
+	Offset int64 `json:"offset"`
+}
+
+// LoadState reads the state file at path.
+func LoadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export state: %w", err)
+	}
+
+	var s State
+	if err = json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("invalid export state %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// Save writes the state to path, replacing the previous state in a single rename so an
+// interrupted save never leaves a corrupt state file.
+func (s *State) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// LastRunPath returns the path of the file in dir holding the time the last incremental export
+// of a query and view started. Exports of deleted assets have their own last run.
+func LastRunPath(dir string, q Query, viewID string) string {
+	name := fmt.Sprintf(".iconik-io_%s_%s", q.Key(), viewID)
+	if q.Deleted {
+		name += "_deleted"
+	}
+	return filepath.Join(dir, name+".last-run")
+}
+
+// LoadLastRun reads the time of the last run from the file at path. It returns the zero
+// time if there hasn't been a run yet.
+func LoadLastRun(path string) (time.Time, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last run %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+// SaveLastRun writes the time of the last run to the file at path.
+func SaveLastRun(path string, t time.Time) error {
+	return os.WriteFile(path, []byte(t.UTC().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// ResumableWriter wraps a Writer, saving the State of the export after each page is written.
+type ResumableWriter struct {
+	Writer
+	state  *State
+	path   string
+	offset func() (int64, error)
+}
+
+// NewResumableWriter returns a new ResumableWriter which saves state to the path file. offset
+// returns the size of the output file once a page has been written and flushed.
+func (svc *Svc) NewResumableWriter(w Writer, state *State, path string, offset func() (int64, error)) *ResumableWriter {
+	return &ResumableWriter{
+		Writer: w,
+		state:  state,
+		path:   path,
+		offset: offset,
+	}
+}
+
+// WriteObjects writes a page of objects, then saves the cursor of the last one.
+func (rw *ResumableWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	if err := rw.Writer.WriteObjects(viewFields, objs); err != nil {
+		return err
+	}
+	if len(objs) == 0 {
+		return nil
+	}
+
+	offset, err := rw.offset()
+	if err != nil {
+		return err
+	}
+
+	rw.state.SearchAfter = objs[len(objs)-1].Sort
+	rw.state.Rows += len(objs)
+	rw.state.Offset = offset
+
+	return rw.state.Save(rw.path)
+}
@@ -0,0 +1,190 @@
+package output
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+)
+
+// statsColumns are the columns of the stats report, which has a row for each facet value
+// followed by a row for the file sizes of each media type.
+var statsColumns = []string{"facet", "value", "assets", "total_bytes", "average_bytes"}
+
+// fileSizeFacet names the rows of the stats report holding the file sizes of a media type.
+const fileSizeFacet = "file_size"
+
+// Stats is the composition of the assets selected by a search: the number holding each value
+// of the search facets, and the size of their files by media type.
+type Stats struct {
+	// Assets is the number of assets selected.
+	Assets int
+	// Facets are the facets of the search, in the order they were asked for.
+	Facets []Facet
+	// MediaTypes totals the files of the assets of each media type, largest first.
+	MediaTypes []MediaTypeStats
+}
+
+// Facet is the number of assets holding each value of a facet field.
+type Facet struct {
+	Name    string
+	Buckets []searchdomain.BucketDTO
+	// Other counts the assets with values left out of the buckets.
+	Other int
+}
+
+// MediaTypeStats totals the files of the assets of a single media type.
+type MediaTypeStats struct {
+	MediaType string
+	Assets    int
+	Bytes     int64
+}
+
+// Average returns the average size of the files of an asset of the media type, in bytes.
+func (m MediaTypeStats) Average() int64 {
+	if m.Assets == 0 {
+		return 0
+	}
+	return m.Bytes / int64(m.Assets)
+}
+
+// Stats pages through the assets selected by q, counting them by their facet values as iconik
+// returns them, and summing the sizes of every file of each asset by its media type. Conditions
+// the search can't match would leave the facet counts wrong, so q can't have any. The search
+// matches the values iconik holds, rather than those written to an export.
+func (svc *Svc) Stats(ctx context.Context, q Query) (*Stats, error) {
+	if _, ok := q.conditionTerms(nil); !ok {
+		return nil, errors.New("stats can only be narrowed by conditions the search can match, such as name=value")
+	}
+
+	s := svc.Search(q, nil)
+	pager := svc.NewPager(ctx, s, nil)
+	defer pager.Close()
+
+	stats := &Stats{}
+	sizes := make(map[string]*MediaTypeStats)
+	first := true
+
+	for pager.Next() {
+		if first {
+			stats.Facets = facets(s.Facets, pager.Facets())
+			first = false
+		}
+
+		for _, obj := range pager.Objects() {
+			if obj.ObjectType == record.ObjectTypeCollections {
+				continue
+			}
+			stats.Assets++
+
+			mediaType := obj.MediaType
+			if mediaType == "" {
+				mediaType = "unknown"
+			}
+			m, ok := sizes[mediaType]
+			if !ok {
+				m = &MediaTypeStats{MediaType: mediaType}
+				sizes[mediaType] = m
+			}
+			m.Assets++
+			for _, f := range obj.Files {
+				m.Bytes += int64(f.Size)
+			}
+		}
+	}
+	if err := pager.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range sizes {
+		stats.MediaTypes = append(stats.MediaTypes, *m)
+	}
+	slices.SortFunc(stats.MediaTypes, func(a, b MediaTypeStats) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MediaType, b.MediaType)
+	})
+
+	return stats, nil
+}
+
+// facets returns the facets of the search results named in names, in that order. Facets
+// iconik returned no counts for are left out.
+func facets(names []string, results map[string]searchdomain.FacetDTO) []Facet {
+	var fs []Facet
+	for _, name := range names {
+		f, ok := results[name]
+		if !ok {
+			continue
+		}
+		fs = append(fs, Facet{Name: name, Buckets: f.Buckets, Other: f.Other})
+	}
+	return fs
+}
+
+// Rows returns the stats as the rows of a table, starting with the header row.
+func (st *Stats) Rows() [][]string {
+	rows := [][]string{statsColumns}
+	for _, f := range st.Facets {
+		for _, b := range f.Buckets {
+			rows = append(rows, []string{f.Name, b.Value, strconv.Itoa(b.Count), "", ""})
+		}
+		if f.Other > 0 {
+			rows = append(rows, []string{f.Name, "other", strconv.Itoa(f.Other), "", ""})
+		}
+	}
+
+	for _, m := range st.MediaTypes {
+		rows = append(rows, []string{fileSizeFacet, m.MediaType, strconv.Itoa(m.Assets), strconv.FormatInt(m.Bytes, 10), strconv.FormatInt(m.Average(), 10)})
+	}
+
+	return rows
+}
+
+// WriteSummary writes the stats as a human readable summary, with the count of each facet
+// value and the file sizes of each media type.
+func (st *Stats) WriteSummary(w io.Writer) error {
+	fmt.Fprintf(w, "Assets: %d\n", st.Assets)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range st.Facets {
+		fmt.Fprintf(tw, "\n%s\n", f.Name)
+		for _, b := range f.Buckets {
+			fmt.Fprintf(tw, "  %s\t%d\n", b.Value, b.Count)
+		}
+		if f.Other > 0 {
+			fmt.Fprintf(tw, "  other\t%d\n", f.Other)
+		}
+	}
+
+	fmt.Fprintln(tw, "\nFile size by media type")
+	fmt.Fprintln(tw, "  Media type\tAssets\tTotal\tAverage")
+	for _, m := range st.MediaTypes {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\n", m.MediaType, m.Assets, formatBytes(m.Bytes), formatBytes(m.Average()))
+	}
+
+	return tw.Flush()
+}
+
+// formatBytes formats a size in bytes with a binary unit, such as 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
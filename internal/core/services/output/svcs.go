@@ -2,18 +2,22 @@ package output
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/api/iconik"
 	colldomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/assets/collections"
 	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
 	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/assets/collections"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/assets/segments"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/metadata"
 	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/ports/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Svc is a struct that implements the iconik servicer ports.
@@ -21,6 +25,7 @@ type Svc struct {
 	collSvc     collections.Servicer
 	metadataSvc metadata.Servicer
 	searchSvc   search.Servicer
+	segmentSvc  segments.Servicer
 }
 
 // New is a function that returns a new instance of iconik Svc struct.
@@ -28,11 +33,13 @@ func New(
 	collSvc collections.Servicer,
 	metadataSvc metadata.Servicer,
 	searchSvc search.Servicer,
+	segmentSvc segments.Servicer,
 ) *Svc {
 	return &Svc{
 		collSvc:     collSvc,
 		metadataSvc: metadataSvc,
 		searchSvc:   searchSvc,
+		segmentSvc:  segmentSvc,
 	}
 }
 
@@ -46,10 +53,25 @@ func (svc *Svc) GetMetadataView(ctx context.Context, viewID string) (metadatadom
 	if view.Errors != nil {
 		return metadatadomain.DTO{}, fmt.Errorf("%v", view.Errors)
 	}
+	view.ID = viewID
 
 	return view, nil
 }
 
+// GetMetadataViews retrieves each of the Metadata views from the iconik API.
+func (svc *Svc) GetMetadataViews(ctx context.Context, viewIDs []string) ([]metadatadomain.DTO, error) {
+	views := make([]metadatadomain.DTO, 0, len(viewIDs))
+	for _, viewID := range viewIDs {
+		view, err := svc.GetMetadataView(ctx, viewID)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %w", viewID, err)
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
 // GetCollection retrieves a Collection from the iconik API.
 func (svc *Svc) GetCollection(ctx context.Context, collectionID string) (colldomain.CollectionDTO, error) {
 	coll, err := svc.collSvc.GetCollection(ctx, iconik.CollectionsPath, collectionID)
@@ -60,66 +82,34 @@ func (svc *Svc) GetCollection(ctx context.Context, collectionID string) (colldom
 	return coll, nil
 }
 
-// ProcessPage processes each page of the iconik search results using search_after pagination.
-func (svc *Svc) ProcessPage(ctx context.Context, viewFields []metadatadomain.ViewFieldDTO, collectionID string, searchAfter []interface{}, w *csv.Writer) error {
-	s := searchdomain.Search{
-		DocTypes:      []string{"assets", "collections"},
-		Facets:        []string{"object_type", "media_type", "archive_status", "type", "format", "is_online", "approval_status"},
-		IncludeFields: []string{"id", "title", "files", "in_collections", "metadata", "files.size", "media_type"},
-		Sort: []searchdomain.Sort{
-			{Name: "date_created", Order: "desc"},
-		},
-		Query: "",
-		Filter: searchdomain.Filter{
-			Operator: "AND",
-			Terms: []searchdomain.Term{
-				{Name: "ancestor_collections", ValueIn: []string{collectionID}},
-				{Name: "status", ValueIn: []string{"ACTIVE"}},
-			},
-		},
-		FacetsFilters: []searchdomain.FacetsFilter{
-			{Name: "object_type", ValueIn: []string{"assets"}},
-		},
-		SearchFields: []string{"title", "description", "segment_text", "file_names", "metadata", "transcription_text"},
-		SearchAfter:  []interface{}{},
-	}
-
-	if len(searchAfter) > 0 {
-		s.SearchAfter = searchAfter
-	}
-
-	sPayload, err := json.Marshal(s)
-	if err != nil {
-		return err
-	}
-
-	results, err := svc.searchSvc.Search(ctx, iconik.SearchPath, sPayload)
-	if err != nil {
-		return err
-	}
-
-	toWrite, err := svc.FormatResultsObjects(viewFields, results.Objects)
-	if err != nil {
-		return err
-	}
-
-	if err = w.WriteAll(toWrite); err != nil {
-		return err
-	}
+// ProcessPage writes each page of the iconik search results for q to w, starting after the
+// searchAfter sort values if any are given. Pages are fetched with search_after pagination,
+// the next page being fetched while the current one is written. Objects not meeting the
+// conditions of q, with dates and times written in loc, are left out.
+func (svc *Svc) ProcessPage(ctx context.Context, viewFields []metadatadomain.ViewFieldDTO, q Query, searchAfter []interface{}, loc *time.Location, w Writer) error {
+	pager := svc.NewPager(ctx, svc.Search(q, viewFields), searchAfter)
+	defer pager.Close()
 
-	if len(results.Objects) > 0 {
-		lastObject := results.Objects[len(results.Objects)-1]
-		searchAfterNew := lastObject.Sort
-		if err = svc.ProcessPage(ctx, viewFields, collectionID, searchAfterNew, w); err != nil {
+	for pager.Next() {
+		objs := q.match(viewFields, pager.Objects(), loc)
+		if len(objs) == 0 {
+			continue
+		}
+		if err := w.WriteObjects(viewFields, objs); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return pager.Err()
 }
 
-// FormatResultsObjects formats the results of a search into a 2d slice, ready for writing.
-func (svc *Svc) FormatResultsObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) ([][]string, error) {
+// FormatResultsObjects formats the results of a search into a 2d slice, ready for writing. Each
+// row holds the layout's system columns followed by the view fields, unless the layout is long.
+func (svc *Svc) FormatResultsObjects(layout Layout, viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) ([][]string, error) {
+	if layout.Long {
+		return longRows(layout, viewFields, objs), nil
+	}
+
 	var metadataFile [][]string
 	var csvColumnsName []string
 
@@ -130,17 +120,12 @@ func (svc *Svc) FormatResultsObjects(viewFields []metadatadomain.ViewFieldDTO, o
 	}
 
 	numColumns := len(csvColumnsName)
+	types := fieldTypes(viewFields)
+	loc := layout.Location()
 
 	for _, object := range objs {
-		row := make([]string, numColumns+4)
-		row[0] = object.ID
-		row[1] = "N/A"
-		row[2] = "N/A"
-		if len(object.Files) > 0 {
-			row[1] = object.Files[0].OriginalName
-			row[2] = strconv.Itoa(object.Files[0].Size)
-		}
-		row[3] = object.Title
+		row := make([]string, numColumns+len(layout.Columns))
+		offset := len(layout.Columns)
 
 		for i := 0; i < numColumns; i++ {
 			metadataField := csvColumnsName[i]
@@ -148,52 +133,203 @@ func (svc *Svc) FormatResultsObjects(viewFields []metadatadomain.ViewFieldDTO, o
 			result := make([]string, len(metadataValue))
 
 			for index, elem := range metadataValue {
-				switch val := elem.(type) {
-				case string:
-					str := val
-					if strings.HasPrefix(str, " ") {
-						str = strings.TrimLeft(str, " ")
-					}
-					if strings.HasSuffix(str, " ") {
-						str = strings.TrimRight(str, " ")
-					}
-					result[index] = str
-				case bool:
-					result[index] = fmt.Sprintf("%t", val)
-				case int:
-					result[index] = fmt.Sprintf("%d", val)
-				case float64:
-					result[index] = fmt.Sprintf("%d", int(val))
-				default:
-					result[index] = fmt.Sprintf("%d", val)
+				result[index] = formatValue(elem, types[metadataField], loc)
+			}
+
+			row[i+offset] = record.JoinValues(result)
+		}
+
+		collectionPath, err := layout.collectionPath(object)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range layout.rowFiles(object) {
+			fileRow := append([]string{}, row...)
+			for i, c := range layout.Columns {
+				if c == CollectionPathColumn {
+					fileRow[i] = collectionPath
+					continue
 				}
+				fileRow[i] = systemColumns[c].value(object, file)
 			}
+			metadataFile = append(metadataFile, fileRow)
+		}
+	}
+
+	return metadataFile, nil
+}
+
+// FormatResultsRecords formats the results of a search into records, keeping every value of
+// each view field with its original type.
+func (svc *Svc) FormatResultsRecords(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) []record.Record {
+	records := make([]record.Record, 0, len(objs))
+	for _, object := range objs {
+		rec := record.New(object.ID, "", object.Title)
+		rec.ObjectType = object.ObjectType
+		if len(object.Files) > 0 {
+			size := object.Files[0].Size
+			rec.OriginalName = object.Files[0].OriginalName
+			rec.Size = &size
+		}
 
-			if len(result) > 1 {
-				row[i+4] = strings.Join(result, ",")
-			} else {
-				row[i+4] = strings.Join(result, "")
+		for _, field := range viewFields {
+			if field.Name == "__separator__" {
+				continue
 			}
+			rec.Set(field.Name, object.Metadata[field.Name]...)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records
+}
 
+// formatValue formats a single metadata value as a string, according to the type of its view
+// field. Floats keep their full precision, dates and date times are written in ISO 8601 in the
+// loc timezone, and nested objects are written as JSON, so every value re-imports as it was.
+func formatValue(elem interface{}, fieldType string, loc *time.Location) string {
+	switch val := elem.(type) {
+	case nil:
+		return ""
+	case string:
+		str := strings.TrimSpace(val)
+		switch fieldType {
+		case "date", "datetime":
+			return formatDate(str, fieldType, loc)
 		}
+		return str
+	case bool:
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		if fieldType == "integer" && val == math.Trunc(val) {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
+
+// formatDate converts a date time value to the loc timezone. Dates are calendar days rather than
+// instants, so they are written as the day they hold without converting them. Values which
+// don't parse are returned unchanged.
+func formatDate(str, fieldType string, loc *time.Location) string {
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return str
+	}
 
-		metadataFile = append(metadataFile, row)
+	if fieldType == "date" {
+		return t.Format(time.DateOnly)
 	}
+	return t.In(loc).Format(time.RFC3339Nano)
+}
 
-	return metadataFile, nil
+// fieldTypes returns the type of each view field, keyed by field name.
+func fieldTypes(viewFields []metadatadomain.ViewFieldDTO) map[string]string {
+	types := make(map[string]string, len(viewFields))
+	for _, field := range viewFields {
+		types[field.Name] = field.FieldType
+	}
+	return types
 }
 
-// Headers writers the headers provided by a slice of ViewFieldDTO to a 2d slice, ready for writing.
-func (svc *Svc) Headers(viewFields []metadatadomain.ViewFieldDTO) [][]string {
+// Headers writers the system columns and the headers provided by a slice of ViewFieldDTO to a
+// 2d slice, ready for writing. The first row holds the view field labels, followed by a row of
+// their names when the layout asks for it. System columns are written by name in both rows.
+func (svc *Svc) Headers(layout Layout, viewFields []metadatadomain.ViewFieldDTO) [][]string {
+	if layout.Long {
+		return longHeaders(layout)
+	}
+
 	var metadataFile [][]string
 	var csvColumnsLabel []string
+	var csvColumnsName []string
 	for _, field := range viewFields {
 		if field.Name != "__separator__" {
 			csvColumnsLabel = append(csvColumnsLabel, field.Label)
+			csvColumnsName = append(csvColumnsName, field.Name)
 		}
 	}
 
-	headerRow := append([]string{"id", "original_name", "size", "title"}, csvColumnsLabel...)
+	headerRow := append(append([]string{}, layout.Columns...), csvColumnsLabel...)
+	metadataFile = append(metadataFile, headerRow)
+
+	if layout.FieldNames {
+		nameRow := append(append([]string{}, layout.Columns...), csvColumnsName...)
+		metadataFile = append(metadataFile, nameRow)
+	}
+
+	return metadataFile
+}
+
+// DuplicateLabels returns the labels shared by more than one view field, which can't be told
+// apart by a header row of labels alone.
+func (svc *Svc) DuplicateLabels(viewFields []metadatadomain.ViewFieldDTO) []string {
+	var duplicates []string
+	count := make(map[string]int)
+	for _, field := range viewFields {
+		if field.Name == "__separator__" {
+			continue
+		}
+		count[field.Label]++
+		if count[field.Label] == 2 {
+			duplicates = append(duplicates, field.Label)
+		}
+	}
+
+	return duplicates
+}
+
+// Columns describes the type and drop-down options of each column written by Headers,
+// for output formats which support typed cells.
+func (svc *Svc) Columns(layout Layout, viewFields []metadatadomain.ViewFieldDTO) []xlsxio.Column {
+	if layout.Long {
+		return longColumns(layout)
+	}
+
+	columns := make([]xlsxio.Column, 0, len(layout.Columns)+len(viewFields))
+	for _, c := range layout.Columns {
+		columns = append(columns, xlsxio.Column{Label: c, Type: systemColumns[c].typ})
+	}
+
+	for _, field := range viewFields {
+		if field.Name == "__separator__" {
+			continue
+		}
+
+		col := xlsxio.Column{Label: field.Label, Type: xlsxio.TypeText}
+		switch field.FieldType {
+		case "integer":
+			col.Type = xlsxio.TypeInteger
+		case "float":
+			col.Type = xlsxio.TypeFloat
+		case "boolean":
+			col.Type = xlsxio.TypeBoolean
+		case "date":
+			col.Type = xlsxio.TypeDate
+		case "datetime":
+			col.Type = xlsxio.TypeDateTime
+		}
+
+		for _, opt := range field.Options {
+			col.Options = append(col.Options, opt.Value)
+		}
+
+		columns = append(columns, col)
+	}
 
-	return append(metadataFile, headerRow)
+	return columns
 }
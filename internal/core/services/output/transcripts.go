@@ -0,0 +1,113 @@
+package output
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/record"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/subtitles"
+)
+
+// TranscriptWriter writes the transcript of each asset in the search results as SRT and WebVTT
+// subtitle files, and optionally as plain text. Assets without a transcript are skipped.
+type TranscriptWriter struct {
+	ctx     context.Context
+	svc     *Svc
+	dir     string
+	text    bool
+	names   map[string]bool
+	written int
+	skipped int
+}
+
+// NewTranscriptWriter returns a new TranscriptWriter which writes subtitle files to the dir
+// folder, and plain text files too if text is set.
+func (svc *Svc) NewTranscriptWriter(ctx context.Context, dir string, text bool) *TranscriptWriter {
+	return &TranscriptWriter{
+		ctx:   ctx,
+		svc:   svc,
+		dir:   dir,
+		text:  text,
+		names: make(map[string]bool),
+	}
+}
+
+// WriteHeader creates the folder the transcripts are written to.
+func (tw *TranscriptWriter) WriteHeader(_ []metadatadomain.ViewFieldDTO) error {
+	return os.MkdirAll(tw.dir, 0755)
+}
+
+// WriteObjects fetches the transcript of each asset and writes its files, named after the
+// asset's original filename. Collections have no transcripts.
+func (tw *TranscriptWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	for _, rec := range tw.svc.FormatResultsRecords(viewFields, objs) {
+		if rec.ObjectType == record.ObjectTypeCollections {
+			continue
+		}
+
+		segs, err := tw.svc.segmentSvc.GetTranscription(tw.ctx, rec.ID)
+		if err != nil {
+			return err
+		}
+
+		cues := make([]subtitles.Cue, 0, len(segs))
+		for _, seg := range segs {
+			if seg.SegmentText == "" {
+				continue
+			}
+			cues = append(cues, subtitles.Cue{Start: seg.TimeStartMilliseconds, End: seg.TimeEndMilliseconds, Text: seg.SegmentText})
+		}
+		if len(cues) == 0 {
+			tw.skipped++
+			continue
+		}
+
+		// every file of an asset shares its base name, so reserve it once for all of them.
+		base := uniqueName(tw.names, rec.ID, rec.OriginalName, "")
+		files := map[string]func(io.Writer, []subtitles.Cue) error{
+			".srt": subtitles.WriteSRT,
+			".vtt": subtitles.WriteVTT,
+		}
+		if tw.text {
+			files[".txt"] = subtitles.WriteText
+		}
+
+		for ext, write := range files {
+			if err = writeFile(filepath.Join(tw.dir, base+ext), cues, write); err != nil {
+				return err
+			}
+		}
+		tw.written++
+	}
+
+	return nil
+}
+
+// Counts returns the number of assets whose transcripts were written, and the number skipped
+// as they have no transcript.
+func (tw *TranscriptWriter) Counts() (int, int) {
+	return tw.written, tw.skipped
+}
+
+func writeFile(path string, cues []subtitles.Cue, write func(io.Writer, []subtitles.Cue) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = write(f, cues); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Close does nothing, as each file is closed once written.
+func (tw *TranscriptWriter) Close() error {
+	return nil
+}
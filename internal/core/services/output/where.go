@@ -0,0 +1,153 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xlsxio"
+)
+
+// Condition is a condition on the values of a metadata field or system column which an asset
+// must meet to be exported.
+type Condition struct {
+	Name string `json:"name"`
+	// Values are the values the field must hold any of. No values matches a field without
+	// values.
+	Values []string `json:"values,omitempty"`
+	// Negate inverts the condition, matching a field holding none of the values, or holding
+	// any value at all if there are none.
+	Negate bool `json:"negate,omitempty"`
+	// Search is set when the search can narrow the results to the values too, before they are
+	// matched exactly.
+	Search bool `json:"search,omitempty"`
+}
+
+// ParseCondition parses a condition from name=value1,value2, where the field holds any of the
+// values, or name!=value1,value2, where it holds none of them. Leaving out the values matches
+// a field without values, or with any value for !=.
+func ParseCondition(s string) (Condition, error) {
+	name, value, ok := strings.Cut(s, "=")
+	c := Condition{Name: strings.TrimSpace(name)}
+	if strings.HasSuffix(c.Name, "!") {
+		c.Name, c.Negate = strings.TrimSpace(strings.TrimSuffix(c.Name, "!")), true
+	}
+	if !ok || c.Name == "" {
+		return Condition{}, fmt.Errorf("invalid condition %s, expected name=, name!=, name=value, name!=value or name=value1,value2", s)
+	}
+	// collection paths are resolved after the search, so there is nothing to match them against.
+	if c.Name == CollectionPathColumn {
+		return Condition{}, fmt.Errorf("can't match conditions on %s", CollectionPathColumn)
+	}
+
+	if value = strings.TrimSpace(value); value != "" {
+		for _, v := range strings.Split(value, ",") {
+			c.Values = append(c.Values, strings.TrimSpace(v))
+		}
+	}
+
+	return c, nil
+}
+
+// Where adds the condition c to the query. Every condition is matched against each page of
+// results, comparing the values as they are written to the export, so name=value and
+// name!=value select complementary assets. A metadata field or asset level column holding any
+// of a set of values is also added to the search as a filter term, to narrow the results.
+func (q *Query) Where(c Condition) {
+	_, ok := searchField(c.Name)
+	c.Search = ok && !c.Negate && len(c.Values) > 0
+	q.Conditions = append(q.Conditions, c)
+}
+
+// conditionTerms returns the filter terms of the conditions the search can narrow the results
+// to, and whether every condition has one. The search compares the values iconik holds rather
+// than those written to the export, so fields whose values are formatted, such as dates and
+// numbers, are only matched against the results. Metadata fields are taken to hold their
+// values as they are written unless their type is in types.
+func (q Query) conditionTerms(types map[string]string) ([]searchdomain.Term, bool) {
+	var terms []searchdomain.Term
+	all := true
+	for _, c := range q.Conditions {
+		field, _ := searchField(c.Name)
+		typ := types[c.Name]
+		if col, ok := systemColumns[c.Name]; ok {
+			typ = col.typ
+		}
+
+		switch {
+		case !c.Search:
+			all = false
+		case typ == xlsxio.TypeDate || typ == xlsxio.TypeDateTime || typ == xlsxio.TypeInteger || typ == xlsxio.TypeFloat:
+			all = false
+		default:
+			terms = append(terms, searchdomain.Term{Name: field, ValueIn: c.Values})
+		}
+	}
+	return terms, all
+}
+
+// match returns the objects meeting every condition of the query, with dates and times written
+// in loc.
+func (q Query) match(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO, loc *time.Location) []searchdomain.ObjectDTO {
+	if len(q.Conditions) == 0 {
+		return objs
+	}
+
+	types := fieldTypes(viewFields)
+	matched := make([]searchdomain.ObjectDTO, 0, len(objs))
+	for _, obj := range objs {
+		ok := true
+		for _, c := range q.Conditions {
+			if !c.matches(conditionValues(obj, c.Name, types, loc)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, obj)
+		}
+	}
+
+	return matched
+}
+
+// matches reports whether a field holding values meets the condition.
+func (c Condition) matches(values []string) bool {
+	var found bool
+	if len(c.Values) == 0 {
+		found = len(values) == 0
+	} else {
+		found = slices.ContainsFunc(values, func(v string) bool { return slices.Contains(c.Values, v) })
+	}
+	return found != c.Negate
+}
+
+// conditionValues returns the non empty values of the system column or metadata field name of
+// obj, as they are written to an export in loc. File level columns hold the values of every file.
+func conditionValues(obj searchdomain.ObjectDTO, name string, types map[string]string, loc *time.Location) []string {
+	var values []string
+	add := func(v string) {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+
+	if col, ok := systemColumns[name]; ok {
+		if !col.fileLevel {
+			add(col.value(obj, nil))
+			return values
+		}
+		for i := range obj.Files {
+			add(col.value(obj, &obj.Files[i]))
+		}
+		return values
+	}
+
+	for _, elem := range obj.Metadata[name] {
+		add(formatValue(elem, types[name], loc))
+	}
+	return values
+}
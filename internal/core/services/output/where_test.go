@@ -0,0 +1,99 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+var whereViewFields = []metadatadomain.ViewFieldDTO{
+	{Name: "rights_holder", FieldType: "string"},
+	{Name: "logged", FieldType: "datetime"},
+	{Name: "takes", FieldType: "integer"},
+}
+
+func TestMatch(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objs := []searchdomain.ObjectDTO{
+		{ID: "1", Metadata: map[string][]interface{}{"rights_holder": {"BBC"}, "logged": {"2024-01-31T10:00:00Z"}, "takes": {3.0}}},
+		{ID: "2", Metadata: map[string][]interface{}{"rights_holder": {"BBC Studios"}, "logged": {"2024-01-31T11:00:00Z"}}},
+		{ID: "3", Metadata: map[string][]interface{}{}},
+	}
+
+	tests := []struct {
+		where string
+		loc   *time.Location
+		want  []string
+	}{
+		{"rights_holder=BBC", time.UTC, []string{"1"}},
+		{"rights_holder!=BBC", time.UTC, []string{"2", "3"}},
+		{"rights_holder=BBC,BBC Studios", time.UTC, []string{"1", "2"}},
+		{"rights_holder=", time.UTC, []string{"3"}},
+		{"rights_holder!=", time.UTC, []string{"1", "2"}},
+		{"takes=3", time.UTC, []string{"1"}},
+		{"takes!=3", time.UTC, []string{"2", "3"}},
+		{"logged=2024-01-31T10:00:00Z", time.UTC, []string{"1"}},
+		// dates and times are matched as they are written in the export's timezone.
+		{"logged=2024-01-31T11:00:00+01:00", paris, []string{"1"}},
+		{"logged!=2024-01-31T11:00:00+01:00", paris, []string{"2", "3"}},
+		{"logged=2024-01-31T10:00:00Z", paris, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			c, err := ParseCondition(tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var q Query
+			q.Where(c)
+
+			var got []string
+			for _, obj := range q.match(whereViewFields, objs, tt.loc) {
+				got = append(got, obj.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionTerms(t *testing.T) {
+	tests := []struct {
+		where []string
+		terms []searchdomain.Term
+		all   bool
+	}{
+		{[]string{"rights_holder=BBC"}, []searchdomain.Term{{Name: "rights_holder", ValueIn: []string{"BBC"}}}, true},
+		{[]string{"media_type=VIDEO,IMAGE", "duration=1000"}, []searchdomain.Term{{Name: "media_type", ValueIn: []string{"VIDEO", "IMAGE"}}}, false},
+		{[]string{"logged=2024-01-31T10:00:00Z"}, nil, false},
+		{[]string{"takes=3"}, nil, false},
+		{[]string{"rights_holder!=BBC"}, nil, false},
+		{[]string{"rights_holder="}, nil, false},
+		{[]string{"original_name=clip.mov"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		var q Query
+		for _, w := range tt.where {
+			c, err := ParseCondition(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Where(c)
+		}
+
+		terms, all := q.conditionTerms(fieldTypes(whereViewFields))
+		if !reflect.DeepEqual(terms, tt.terms) || all != tt.all {
+			t.Errorf("conditionTerms(%v) = %v, %v, want %v, %v", tt.where, terms, all, tt.terms, tt.all)
+		}
+	}
+}
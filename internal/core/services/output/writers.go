@@ -0,0 +1,192 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/ale"
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+)
+
+// RowWriter is implemented by the writers of each tabular output format.
+type RowWriter interface {
+	WriteAll(records [][]string) error
+}
+
+// Writer writes the search results of an export in an output format.
+type Writer interface {
+	WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error
+	WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error
+	Close() error
+}
+
+// RowCounter is implemented by Writers which count the rows or records they have written,
+// not including any header.
+type RowCounter interface {
+	Rows() int
+}
+
+// TableWriter writes search results as a header row followed by one row per asset.
+type TableWriter struct {
+	svc    *Svc
+	w      RowWriter
+	layout Layout
+	rows   int
+}
+
+// NewTableWriter returns a new TableWriter which writes rows to w in the given layout. If w is
+// an io.Closer, it is closed when the TableWriter is closed.
+func (svc *Svc) NewTableWriter(w RowWriter, layout Layout) *TableWriter {
+	return &TableWriter{
+		svc:    svc,
+		w:      w,
+		layout: layout,
+	}
+}
+
+// WriteHeader writes the header row.
+func (tw *TableWriter) WriteHeader(viewFields []metadatadomain.ViewFieldDTO) error {
+	return tw.w.WriteAll(tw.svc.Headers(tw.layout, viewFields))
+}
+
+// WriteObjects writes a row for each object.
+func (tw *TableWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	rows, err := tw.svc.FormatResultsObjects(tw.layout, viewFields, objs)
+	if err != nil {
+		return err
+	}
+	tw.rows += len(rows)
+
+	return tw.w.WriteAll(rows)
+}
+
+// Rows returns the number of rows written after the header.
+func (tw *TableWriter) Rows() int {
+	return tw.rows
+}
+
+// Close closes the underlying RowWriter if it needs closing.
+func (tw *TableWriter) Close() error {
+	if c, ok := tw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewALEWriter returns a TableWriter which writes an Avid Log Exchange file. The title, original
+// name and ID are written as the clip Name, Source File and iconik ID columns, so editors can
+// merge the metadata into their bins, followed by any other system columns except size.
+func (svc *Svc) NewALEWriter(w io.Writer, heading ale.Heading, layout Layout) *TableWriter {
+	aleLayout := layout
+	aleLayout.Columns, aleLayout.FieldNames = []string{"title", "original_name", "id"}, false
+	for _, c := range layout.Columns {
+		if c != "title" && c != "original_name" && c != "id" && c != "size" {
+			aleLayout.Columns = append(aleLayout.Columns, c)
+		}
+	}
+
+	return svc.NewTableWriter(&aleRowWriter{w: ale.NewWriter(w, heading)}, aleLayout)
+}
+
+// aleRowWriter renames the clip columns of the rows written by a TableWriter to their ALE names.
+type aleRowWriter struct {
+	w      *ale.Writer
+	header bool
+}
+
+// WriteAll writes the rows as clips.
+func (aw *aleRowWriter) WriteAll(records [][]string) error {
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		if !aw.header {
+			rows[i] = append([]string{ale.ColumnName, ale.ColumnSourceFile, ale.ColumnID}, r[3:]...)
+			aw.header = true
+			continue
+		}
+
+		rows[i] = r
+		if r[1] == "N/A" {
+			rows[i][1] = ""
+		}
+	}
+
+	return aw.w.WriteAll(rows)
+}
+
+// Close closes the ALE writer.
+func (aw *aleRowWriter) Close() error {
+	return aw.w.Close()
+}
+
+// RecordWriter writes search results as JSON records, either as a single array
+// or as newline delimited JSON.
+type RecordWriter struct {
+	svc    *Svc
+	w      *bufio.Writer
+	ndjson bool
+	count  int
+}
+
+// NewRecordWriter returns a new RecordWriter which writes to w.
+func (svc *Svc) NewRecordWriter(w io.Writer, ndjson bool) *RecordWriter {
+	return &RecordWriter{
+		svc:    svc,
+		w:      bufio.NewWriter(w),
+		ndjson: ndjson,
+	}
+}
+
+// WriteHeader opens the JSON array. Records carry their own field names, so no header is needed.
+func (rw *RecordWriter) WriteHeader(_ []metadatadomain.ViewFieldDTO) error {
+	if rw.ndjson {
+		return nil
+	}
+	_, err := rw.w.WriteString("[")
+	return err
+}
+
+// WriteObjects writes a record for each object.
+func (rw *RecordWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	for _, rec := range rw.svc.FormatResultsRecords(viewFields, objs) {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case rw.ndjson:
+			b = append(b, '\n')
+		case rw.count > 0:
+			_, err = rw.w.WriteString(",\n")
+		default:
+			_, err = rw.w.WriteString("\n")
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err = rw.w.Write(b); err != nil {
+			return err
+		}
+		rw.count++
+	}
+
+	return rw.w.Flush()
+}
+
+// Rows returns the number of records written.
+func (rw *RecordWriter) Rows() int {
+	return rw.count
+}
+
+// Close closes the JSON array and flushes any buffered output.
+func (rw *RecordWriter) Close() error {
+	if !rw.ndjson {
+		if _, err := rw.w.WriteString("\n]\n"); err != nil {
+			return err
+		}
+	}
+	return rw.w.Flush()
+}
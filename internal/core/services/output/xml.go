@@ -0,0 +1,187 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xmlprofile"
+)
+
+// sources are the asset and file values profiles can map onto elements, besides view fields.
+var sources = map[string]func(obj searchdomain.ObjectDTO) []string{
+	"asset.id":    func(obj searchdomain.ObjectDTO) []string { return nonEmpty(obj.ID) },
+	"asset.title": func(obj searchdomain.ObjectDTO) []string { return nonEmpty(obj.Title) },
+	"asset.media_type": func(obj searchdomain.ObjectDTO) []string {
+		return nonEmpty(obj.MediaType)
+	},
+	"asset.format": func(obj searchdomain.ObjectDTO) []string { return nonEmpty(obj.Format) },
+	"asset.duration": func(obj searchdomain.ObjectDTO) []string {
+		if obj.DurationMilliseconds == 0 {
+			return nil
+		}
+		return []string{strconv.Itoa(obj.DurationMilliseconds)}
+	},
+	"asset.date_created":  func(obj searchdomain.ObjectDTO) []string { return formatTime(obj.DateCreated) },
+	"asset.date_modified": func(obj searchdomain.ObjectDTO) []string { return formatTime(obj.DateModified) },
+	"file.original_name": func(obj searchdomain.ObjectDTO) []string {
+		if len(obj.Files) == 0 {
+			return nil
+		}
+		return nonEmpty(obj.Files[0].OriginalName)
+	},
+	"file.size": func(obj searchdomain.ObjectDTO) []string {
+		if len(obj.Files) == 0 {
+			return nil
+		}
+		return []string{strconv.Itoa(obj.Files[0].Size)}
+	},
+}
+
+const fieldSource = "field."
+
+// XMLWriter writes search results as XML documents built from a profile, either one document
+// per asset or a single document for the whole collection. Every document is validated
+// against the profile's schema before it is written.
+type XMLWriter struct {
+	profile xmlprofile.Profile
+	schema  *xmlprofile.Schema
+	path    string
+	coll    *xmlprofile.Collection
+	loc     *time.Location
+	names   map[string]bool
+}
+
+// NewXMLWriter returns a new XMLWriter. A single document titled title is written to the path
+// file if perCollection is true, otherwise a document per asset is written to the path folder.
+// Date fields are written in the loc timezone.
+func (svc *Svc) NewXMLWriter(path string, profile xmlprofile.Profile, title string, perCollection bool, loc *time.Location) (*XMLWriter, error) {
+	for _, el := range profile.Elements {
+		if _, ok := sources[el.Source]; !ok && el.Source != "" && !strings.HasPrefix(el.Source, fieldSource) {
+			return nil, fmt.Errorf("profile %s element %s has unknown source %s", profile.Name, el.Path, el.Source)
+		}
+	}
+
+	schema, err := profile.LoadSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &XMLWriter{
+		profile: profile,
+		schema:  schema,
+		path:    path,
+		loc:     loc,
+		names:   make(map[string]bool),
+	}
+
+	if perCollection {
+		if xw.coll, err = profile.NewCollection(title); err != nil {
+			return nil, err
+		}
+	}
+
+	return xw, nil
+}
+
+// WriteHeader creates the folder the documents are written to, when writing a document per asset.
+func (xw *XMLWriter) WriteHeader(_ []metadatadomain.ViewFieldDTO) error {
+	if xw.coll != nil {
+		return nil
+	}
+	return os.MkdirAll(xw.path, 0755)
+}
+
+// WriteObjects adds each object to the collection document, or writes a document for each
+// object named after its original filename.
+func (xw *XMLWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	types := fieldTypes(viewFields)
+	for _, obj := range objs {
+		if xw.coll != nil {
+			xw.coll.Add(resolver(obj, types, xw.loc))
+			continue
+		}
+
+		originalName := ""
+		if len(obj.Files) > 0 {
+			originalName = obj.Files[0].OriginalName
+		}
+
+		name := uniqueName(xw.names, obj.ID, originalName, ".xml")
+		if err := xw.write(filepath.Join(xw.path, name), xw.profile.Build(resolver(obj, types, xw.loc))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the collection document, when writing a single document.
+func (xw *XMLWriter) Close() error {
+	if xw.coll == nil {
+		return nil
+	}
+	return xw.write(xw.path, xw.coll.Root())
+}
+
+// write validates a document and writes it to the file at path.
+func (xw *XMLWriter) write(path string, root *xmlprofile.Node) error {
+	if xw.schema != nil {
+		if errs := xw.schema.Validate(root); len(errs) > 0 {
+			return fmt.Errorf("%s is not valid against schema %s: %w", filepath.Base(path), xw.profile.Schema, errors.Join(errs...))
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err = xw.profile.Write(f, root); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// resolver returns the values of each source for an object, formatting view fields by their type.
+func resolver(obj searchdomain.ObjectDTO, types map[string]string, loc *time.Location) xmlprofile.Resolver {
+	return func(source string) []string {
+		if name, ok := strings.CutPrefix(source, fieldSource); ok {
+			values := make([]string, 0, len(obj.Metadata[name]))
+			for _, v := range obj.Metadata[name] {
+				if v == nil {
+					continue
+				}
+				values = append(values, formatValue(v, types[name], loc))
+			}
+			return values
+		}
+
+		if fn, ok := sources[source]; ok {
+			return fn(obj)
+		}
+		return nil
+	}
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+func formatTime(t time.Time) []string {
+	if t.IsZero() {
+		return nil
+	}
+	return []string{t.UTC().Format(time.RFC3339)}
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	metadatadomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/metadata"
+	searchdomain "github.com/base-media-cloud/pd-iconik-io-rd/internal/core/domain/iconik/search"
+	"github.com/base-media-cloud/pd-iconik-io-rd/internal/xmp"
+)
+
+// XMPWriter writes search results as one XMP sidecar file per asset.
+type XMPWriter struct {
+	svc     *Svc
+	dir     string
+	mapping xmp.Mapping
+	loc     *time.Location
+	names   map[string]bool
+}
+
+// NewXMPWriter returns a new XMPWriter which writes sidecars to the dir folder, with dates in
+// the loc timezone.
+func (svc *Svc) NewXMPWriter(dir string, mapping xmp.Mapping, loc *time.Location) *XMPWriter {
+	return &XMPWriter{
+		svc:     svc,
+		dir:     dir,
+		mapping: mapping,
+		loc:     loc,
+		names:   make(map[string]bool),
+	}
+}
+
+// WriteHeader creates the folder the sidecars are written to.
+func (xw *XMPWriter) WriteHeader(_ []metadatadomain.ViewFieldDTO) error {
+	return os.MkdirAll(xw.dir, 0755)
+}
+
+// WriteObjects writes a sidecar for each object, named after its original filename. The title is
+// written to dc:title, and the asset ID and original filename to the custom namespace so the
+// sidecar can be matched back to its asset.
+func (xw *XMPWriter) WriteObjects(viewFields []metadatadomain.ViewFieldDTO, objs []searchdomain.ObjectDTO) error {
+	for _, rec := range xw.svc.FormatResultsRecords(viewFields, objs) {
+		props := []xmp.Property{
+			xmp.NewProperty("dc", "title", rec.Title),
+			xw.mapping.Property(xmp.PropertyID, rec.ID),
+		}
+		if rec.OriginalName != "" {
+			props = append(props, xw.mapping.Property(xmp.PropertyOriginalName, rec.OriginalName))
+		}
+
+		for _, field := range viewFields {
+			fieldValues, ok := rec.MetadataValues[field.Name]
+			if !ok || len(fieldValues.FieldValues) == 0 {
+				continue
+			}
+
+			values := make([]string, 0, len(fieldValues.FieldValues))
+			for _, fv := range fieldValues.FieldValues {
+				if fv.Value == nil {
+					continue
+				}
+				values = append(values, formatValue(fv.Value, field.FieldType, xw.loc))
+			}
+			props = append(props, xw.mapping.Property(field.Name, values...))
+		}
+
+		f, err := os.Create(filepath.Join(xw.dir, uniqueName(xw.names, rec.ID, rec.OriginalName, ".xmp")))
+		if err != nil {
+			return err
+		}
+
+		if err = xmp.Write(f, props, xw.mapping.Custom()); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uniqueName returns the name of an asset's sidecar or document, which is its original filename
+// with the extension replaced. The asset ID is used if there is no filename, and appended if the
+// name has already been used by another asset.
+func uniqueName(names map[string]bool, id, originalName, ext string) string {
+	base := strings.TrimSuffix(filepath.Base(originalName), filepath.Ext(originalName))
+	if originalName == "" || base == "" || base == "." {
+		base = id
+	}
+
+	name := base + ext
+	if names[name] {
+		name = fmt.Sprintf("%s_%s%s", base, id, ext)
+	}
+	names[name] = true
+
+	return name
+}
+
+// Close does nothing, as each sidecar is closed once written.
+func (xw *XMPWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Package csvio provides CSV readers and writers that understand the dialects
+spreadsheet applications produce, such as semicolon delimiters, byte order
+marks and non UTF-8 encodings.
+*/
+package csvio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// Auto is the value used to request auto-detection of a delimiter or encoding.
+	Auto = "auto"
+	// UTF8 is the name of the UTF-8 encoding.
+	UTF8 = "utf-8"
+	// UTF16LE is the name of the little endian UTF-16 encoding.
+	UTF16LE = "utf-16le"
+	// UTF16BE is the name of the big endian UTF-16 encoding.
+	UTF16BE = "utf-16be"
+	// Windows1252 is the name of the Windows-1252 encoding.
+	Windows1252 = "windows-1252"
+
+	// sniffSize is the number of bytes inspected when auto-detecting a dialect.
+	sniffSize = 64 * 1024
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+
+	// candidateDelimiters are the delimiters considered when auto-detecting.
+	candidateDelimiters = []rune{',', ';', '\t', '|'}
+)
+
+// Dialect describes how a CSV file is laid out.
+type Dialect struct {
+	// Delimiter is the field delimiter, or Auto to detect it from the header row.
+	Delimiter string
+	// Encoding is the character encoding, or Auto to detect it from the content.
+	Encoding string
+	// LazyQuotes allows quotes to appear in unquoted fields and non-doubled quotes in quoted fields.
+	LazyQuotes bool
+	// Excel writes a UTF-8 byte order mark and CRLF line endings so Excel opens the file correctly.
+	Excel bool
+	// Append is true when appending to a file which already has its byte order mark.
+	Append bool
+}
+
+// NewReader returns a csv.Reader which reads UTF-8 records from r, stripping any byte order mark
+// and transcoding and splitting them according to the dialect.
+func NewReader(r io.Reader, d Dialect) (*csv.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	enc, bomLen, err := detectEncoding(head, d.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = br.Discard(bomLen); err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = br
+	if enc != nil {
+		src = transform.NewReader(br, enc.NewDecoder())
+	}
+
+	// re-buffer the transcoded stream so the delimiter can be sniffed from UTF-8 content.
+	ur := bufio.NewReaderSize(src, sniffSize)
+	delim, err := delimiter(ur, d.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(ur)
+	cr.Comma = delim
+	cr.LazyQuotes = d.LazyQuotes
+
+	return cr, nil
+}
+
+// NewWriter returns a csv.Writer which writes records to w in the given dialect.
+// Output is always UTF-8. If the dialect is Excel, a byte order mark is written first.
+func NewWriter(w io.Writer, d Dialect) (*csv.Writer, error) {
+	delim := ','
+	if d.Delimiter != "" && d.Delimiter != Auto {
+		var err error
+		delim, err = parseDelimiter(d.Delimiter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d.Excel && !d.Append {
+		if _, err := w.Write(bomUTF8); err != nil {
+			return nil, err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = delim
+	cw.UseCRLF = d.Excel
+
+	return cw, nil
+}
+
+// detectEncoding returns the decoder to use for the given encoding name, along with
+// the length of the byte order mark that should be skipped. A nil encoding means the
+// content is already UTF-8.
+func detectEncoding(head []byte, name string) (encoding.Encoding, int, error) {
+	bomLen := 0
+	var bomEnc encoding.Encoding
+	bomName := ""
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		bomLen, bomName = len(bomUTF8), UTF8
+	case bytes.HasPrefix(head, bomUTF16LE):
+		bomLen, bomName = len(bomUTF16LE), UTF16LE
+		bomEnc = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		bomLen, bomName = len(bomUTF16BE), UTF16BE
+		bomEnc = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+
+	switch strings.ToLower(name) {
+	case "", Auto:
+		if bomName != "" {
+			return bomEnc, bomLen, nil
+		}
+		if utf8.Valid(trimPartialRune(head)) {
+			return nil, 0, nil
+		}
+		return charmap.Windows1252, 0, nil
+	case UTF8, "utf8":
+		if bomName == UTF8 {
+			return nil, bomLen, nil
+		}
+		return nil, 0, nil
+	case UTF16LE:
+		if bomName == UTF16LE {
+			return bomEnc, bomLen, nil
+		}
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), 0, nil
+	case UTF16BE:
+		if bomName == UTF16BE {
+			return bomEnc, bomLen, nil
+		}
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), 0, nil
+	case Windows1252, "cp1252":
+		return charmap.Windows1252, 0, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported encoding %q", name)
+}
+
+// trimPartialRune removes a trailing incomplete UTF-8 sequence which may have been cut
+// off by the sniff buffer, so it isn't mistaken for invalid content.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// delimiter returns the configured delimiter, or sniffs it from the first line of br.
+func delimiter(br *bufio.Reader, name string) (rune, error) {
+	if name != "" && name != Auto {
+		return parseDelimiter(name)
+	}
+
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, err
+	}
+
+	return sniffDelimiter(head), nil
+}
+
+// parseDelimiter converts a delimiter flag value into a rune.
+func parseDelimiter(name string) (rune, error) {
+	switch strings.ToLower(name) {
+	case "tab", `\t`:
+		return '\t', nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(name)
+	if size != len(name) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q", name)
+	}
+
+	return r, nil
+}
+
+// sniffDelimiter counts the candidate delimiters outside of quotes in the first line
+// and returns the most frequent one, defaulting to a comma.
+func sniffDelimiter(head []byte) rune {
+	counts := make(map[rune]int, len(candidateDelimiters))
+	inQuotes := false
+	for _, r := range string(head) {
+		if r == '"' {
+			inQuotes = !inQuotes
+			continue
+		}
+		if inQuotes {
+			continue
+		}
+		if r == '\n' || r == '\r' {
+			break
+		}
+		counts[r]++
+	}
+
+	best := ','
+	for _, c := range candidateDelimiters {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+
+	return best
+}
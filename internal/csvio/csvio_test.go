@@ -0,0 +1,138 @@
+package csvio
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// utf16 encodes s as UTF-16 in the byte order e, without a byte order mark.
+func utf16(t *testing.T, e unicode.Endianness, s string) []byte {
+	t.Helper()
+	b, err := unicode.UTF16(e, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNewReader(t *testing.T) {
+	auto := Dialect{Delimiter: Auto, Encoding: Auto}
+	want := [][]string{{"id", "title"}, {"1", "Café"}}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		dialect Dialect
+		want    [][]string
+		err     string
+	}{
+		{"utf-8", []byte("id,title\n1,Café\n"), auto, want, ""},
+		{"utf-8 bom", append(bomUTF8, "id,title\r\n1,Café\r\n"...), auto, want, ""},
+		{"utf-8 bom named", append(bomUTF8, "id,title\n1,Café\n"...), Dialect{Encoding: UTF8}, want, ""},
+		{"utf-16le bom", append(bomUTF16LE, utf16(t, unicode.LittleEndian, "id\ttitle\r\n1\tCafé\r\n")...), auto, want, ""},
+		{"utf-16be bom", append(bomUTF16BE, utf16(t, unicode.BigEndian, "id;title\n1;Café\n")...), auto, want, ""},
+		{"utf-16le named", utf16(t, unicode.LittleEndian, "id,title\n1,Café\n"), Dialect{Encoding: UTF16LE}, want, ""},
+		{"windows-1252 detected", []byte("id,title\n1,Caf\xe9\n"), auto, want, ""},
+		{"windows-1252 named", []byte("id,title\n1,Caf\xe9\n"), Dialect{Encoding: "cp1252"}, want, ""},
+		{"semicolon", []byte("id;title\n1;Café\n"), auto, want, ""},
+		{"tab", []byte("id\ttitle\n1\tCafé\n"), auto, want, ""},
+		{"pipe", []byte("id|title\n1|Café\n"), auto, want, ""},
+		{"quoted delimiters ignored", []byte("\"a;b;c\",title\n1,Café\n"), auto, [][]string{{"a;b;c", "title"}, {"1", "Café"}}, ""},
+		{"only the first line sniffed", []byte("id,title\n1;2;3,Café\n"), auto, [][]string{{"id", "title"}, {"1;2;3", "Café"}}, ""},
+		{"no delimiter", []byte("id\n1\n"), auto, [][]string{{"id"}, {"1"}}, ""},
+		{"named delimiter", []byte("id;title\n1;Café\n"), Dialect{Delimiter: "semicolon"}, want, ""},
+		{"single character delimiter", []byte("id~title\n1~Café\n"), Dialect{Delimiter: "~"}, want, ""},
+		{"invalid delimiter", []byte("id,title\n"), Dialect{Delimiter: "::"}, nil, `invalid delimiter "::"`},
+		{"unsupported encoding", []byte("id,title\n"), Dialect{Encoding: "ebcdic"}, nil, `unsupported encoding "ebcdic"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(tt.input), tt.dialect)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("NewReader() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		head   []byte
+		bomLen int
+		utf8   bool
+	}{
+		{"ascii", []byte("id,title"), 0, true},
+		{"utf-8 bom", append(bomUTF8, "id"...), 3, true},
+		// the sniff buffer can end part way through a character, which is still UTF-8.
+		{"cut off character", []byte("id,Caf\xc3"), 0, true},
+		{"invalid utf-8", []byte("id,Caf\xe9,x"), 0, false},
+		{"utf-16le bom", append(bomUTF16LE, 'i', 0), 2, false},
+		{"utf-16be bom", append(bomUTF16BE, 0, 'i'), 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, bomLen, err := detectEncoding(tt.head, Auto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bomLen != tt.bomLen {
+				t.Errorf("detectEncoding() bom length = %d, want %d", bomLen, tt.bomLen)
+			}
+			if (enc == nil) != tt.utf8 {
+				t.Errorf("detectEncoding() = %v, want UTF-8 %v", enc, tt.utf8)
+			}
+		})
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	rows := [][]string{{"id", "title"}, {"1", "Smith; John"}}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{"default", Dialect{}, "id,title\n1,Smith; John\n"},
+		{"auto", Dialect{Delimiter: Auto}, "id,title\n1,Smith; John\n"},
+		{"semicolon", Dialect{Delimiter: "semicolon"}, "id;title\n1;\"Smith; John\"\n"},
+		{"excel", Dialect{Excel: true}, "\ufeffid,title\r\n1,Smith; John\r\n"},
+		{"excel append", Dialect{Excel: true, Append: true}, "id,title\r\n1,Smith; John\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w, err := NewWriter(&b, tt.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = w.WriteAll(rows); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("NewWriter() wrote %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
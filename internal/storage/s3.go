@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Config is the endpoint and credentials of an S3 compatible object store. An empty endpoint
+// is the AWS endpoint of the region.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3Client is a minimal S3 client, signing requests with AWS Signature Version 4 and
+// addressing buckets by path so it works with MinIO and other S3 compatible stores.
+type s3Client struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+func newS3Client(cfg S3Config) (*s3Client, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("no object store credentials, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	return &s3Client{cfg: cfg, client: &http.Client{}}, nil
+}
+
+// putFile uploads the file at path to the key of the bucket.
+func (c *s3Client) putFile(ctx context.Context, bucket, key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := c.request(ctx, http.MethodPut, bucket, key, nil, f, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// getFile downloads the key of the bucket to the file at path.
+func (c *s3Client) getFile(ctx context.Context, bucket, key, path string) error {
+	req, err := c.request(ctx, http.MethodGet, bucket, key, nil, nil, emptyHash)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// list returns the keys of the bucket starting with prefix.
+func (c *s3Client) list(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := c.request(ctx, http.MethodGet, bucket, "", query, nil, emptyHash)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid listing of s3://%s/%s: %w", bucket, prefix, err)
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// emptyHash is the SHA-256 hash of an empty request body.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// request returns a signed request for the key of the bucket.
+func (c *s3Client) request(ctx context.Context, method, bucket, key string, query url.Values, body io.Reader, payloadHash string) (*http.Request, error) {
+	path := "/" + bucket
+	if key != "" {
+		path += "/" + key
+	}
+	u := c.cfg.Endpoint + encodePath(path)
+	if q := strings.ReplaceAll(query.Encode(), "+", "%20"); q != "" {
+		u += "?" + q
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sign(req, payloadHash, time.Now().UTC())
+
+	return req, nil
+}
+
+// sign signs req with AWS Signature Version 4 at the time now. The query of req must already
+// be in canonical order and encoding.
+func (c *s3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.cfg.SessionToken)
+		headers = append(headers, "x-amz-security-token")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// do sends the request, returning an error for any response other than success.
+func (c *s3Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		_ = xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&e)
+		return nil, fmt.Errorf("%s %s: %s %s %s", req.Method, req.URL.Path, resp.Status, e.Code, e.Message)
+	}
+
+	return resp, nil
+}
+
+// encodePath URI encodes an object path as S3 signs it, escaping every byte other than the
+// unreserved characters and the separators.
+func encodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
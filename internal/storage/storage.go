@@ -0,0 +1,314 @@
+/*
+Package storage delivers exports to where they were asked for, and fetches inputs from where
+they are kept: the local filesystem, the standard streams, or an S3 compatible object store
+addressed by an s3://bucket/key URL.
+*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Stdio is the target naming the standard output for exports, or the standard input for inputs.
+const Stdio = "-"
+
+// Sink is where an export is delivered. Exports are written to a local path, and delivered
+// from it once complete.
+type Sink interface {
+	// Path returns the local path an export named name is written to, before the extension of
+	// its format is added. Sinks for a single file ignore the name.
+	Path(name string) string
+	// Location returns where the file or folder written at the local path is delivered.
+	Location(path string) string
+	// Remote reports whether exports are delivered anywhere other than their local path.
+	Remote() bool
+	// Deliver delivers everything written once the export is complete.
+	Deliver(ctx context.Context) error
+	// Close removes anything left locally which was only written to be delivered.
+	Close() error
+}
+
+// IsS3 reports whether target is an s3:// URL.
+func IsS3(target string) bool {
+	return strings.HasPrefix(target, "s3://")
+}
+
+// IsFolder reports whether target names a folder rather than a file: a path ending in a
+// separator, an existing directory, or a bucket or key prefix ending in a slash.
+func IsFolder(target string) bool {
+	if IsS3(target) {
+		_, key := splitS3(target)
+		return key == "" || strings.HasSuffix(key, "/")
+	}
+	if strings.HasSuffix(target, "/") || strings.HasSuffix(target, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(target)
+	return err == nil && info.IsDir()
+}
+
+// NewSink returns the Sink of the target, which is Stdio, an s3:// URL, or a local path. A
+// folder target takes exports under their generated names, while a file target takes a
+// single export under its own name, with the extension of its format.
+func NewSink(target string, cfg S3Config) (Sink, error) {
+	switch {
+	case target == Stdio:
+		dir, err := os.MkdirTemp("", "iconik-io")
+		if err != nil {
+			return nil, err
+		}
+		return &stdoutSink{dir: dir, w: os.Stdout}, nil
+	case IsS3(target):
+		client, err := newS3Client(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := os.MkdirTemp("", "iconik-io")
+		if err != nil {
+			return nil, err
+		}
+
+		bucket, key := splitS3(target)
+		s := &s3Sink{client: client, bucket: bucket, dir: dir, prefix: key}
+		if !IsFolder(target) {
+			s.prefix, s.name = path.Dir(key)+"/", strings.TrimSuffix(path.Base(key), path.Ext(key))
+			if s.prefix == "./" {
+				s.prefix = ""
+			}
+		}
+		return s, nil
+	}
+
+	if IsFolder(target) {
+		return &fileSink{dir: target}, nil
+	}
+	return &fileSink{dir: filepath.Dir(target), name: strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))}, nil
+}
+
+// fileSink writes exports to the local filesystem, into a folder or as a named file.
+type fileSink struct {
+	dir  string
+	name string
+}
+
+func (s *fileSink) Path(name string) string {
+	if s.name != "" {
+		name = s.name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s *fileSink) Location(path string) string { return path }
+
+func (s *fileSink) Remote() bool { return false }
+
+func (s *fileSink) Deliver(_ context.Context) error { return nil }
+
+func (s *fileSink) Close() error { return nil }
+
+// stdoutSink takes an export of a single file. Most formats write it straight to the standard
+// output, while those written to a path are written to a temporary folder, then copied to it.
+type stdoutSink struct {
+	dir string
+	w   io.Writer
+}
+
+func (s *stdoutSink) Path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *stdoutSink) Location(_ string) string { return "standard output" }
+
+func (s *stdoutSink) Remote() bool { return true }
+
+// Deliver copies an export written to the temporary folder to the standard output, if it
+// wasn't written there directly. Exports of several files can't be written to a single stream.
+func (s *stdoutSink) Deliver(_ context.Context) error {
+	if entries, err := os.ReadDir(s.dir); err != nil || len(entries) == 0 {
+		return err
+	}
+
+	files, err := walk(s.dir)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("only exports of a single file can be written to the standard output, this export has %d", len(files))
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, files[0]))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(s.w, f)
+	return err
+}
+
+func (s *stdoutSink) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// s3Sink writes an export to a temporary folder, then uploads every file written under the
+// key prefix, keeping their paths below the folder.
+type s3Sink struct {
+	client *s3Client
+	bucket string
+	prefix string
+	name   string
+	dir    string
+}
+
+func (s *s3Sink) Path(name string) string {
+	if s.name != "" {
+		name = s.name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+func (s *s3Sink) Location(p string) string {
+	rel, err := filepath.Rel(s.dir, p)
+	if err != nil {
+		return p
+	}
+	return fmt.Sprintf("s3://%s/%s%s", s.bucket, s.prefix, filepath.ToSlash(rel))
+}
+
+func (s *s3Sink) Remote() bool { return true }
+
+// Deliver uploads every file of the export.
+func (s *s3Sink) Deliver(ctx context.Context) error {
+	files, err := walk(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		if err = s.client.putFile(ctx, s.bucket, s.prefix+filepath.ToSlash(rel), filepath.Join(s.dir, rel)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", rel, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *s3Sink) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// Fetch returns a local path holding the input target, which is Stdio, an s3:// URL or a
+// local path. The standard input and objects are copied to a temporary folder, which the
+// returned function removes. An s3:// URL ending in a slash fetches every object under it as
+// a folder.
+func Fetch(ctx context.Context, target string, cfg S3Config) (string, func(), error) {
+	if target != Stdio && !IsS3(target) {
+		return target, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "iconik-io")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	local, err := fetch(ctx, target, cfg, dir)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return local, cleanup, nil
+}
+
+func fetch(ctx context.Context, target string, cfg S3Config, dir string) (string, error) {
+	if target == Stdio {
+		local := filepath.Join(dir, "stdin")
+		f, err := os.Create(local)
+		if err != nil {
+			return "", err
+		}
+		if _, err = io.Copy(f, os.Stdin); err != nil {
+			f.Close()
+			return "", err
+		}
+		return local, f.Close()
+	}
+
+	client, err := newS3Client(cfg)
+	if err != nil {
+		return "", err
+	}
+	bucket, key := splitS3(target)
+
+	if !IsFolder(target) {
+		local := filepath.Join(dir, path.Base(key))
+		return local, client.getFile(ctx, bucket, key, local)
+	}
+
+	keys, err := client.list(ctx, bucket, key)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no objects found under %s", target)
+	}
+
+	local := filepath.Join(dir, "input")
+	for _, k := range keys {
+		rel := strings.TrimPrefix(k, key)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		p := filepath.Join(local, filepath.FromSlash(rel))
+		if !strings.HasPrefix(p, local+string(filepath.Separator)) {
+			return "", fmt.Errorf("invalid object key %s", k)
+		}
+		if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return "", err
+		}
+		if err = client.getFile(ctx, bucket, k, p); err != nil {
+			return "", err
+		}
+	}
+
+	return local, nil
+}
+
+// splitS3 splits an s3:// URL into its bucket and key.
+func splitS3(target string) (string, string) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(target, "s3://"), "/")
+	return bucket, key
+}
+
+// walk returns the paths of the files under dir, relative to it.
+func walk(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("the export wrote no files")
+	}
+
+	return files, nil
+}
@@ -0,0 +1,70 @@
+/*
+Package subtitles writes timed transcript cues as SubRip (SRT) and WebVTT subtitle files, or as
+plain text.
+*/
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Cue is a line of a transcript shown between two times in milliseconds.
+type Cue struct {
+	Start int64
+	End   int64
+	Text  string
+}
+
+// WriteSRT writes cues as a SubRip file, numbering them from 1.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		fmt.Fprintf(bw, "%d\r\n%s --> %s\r\n%s\r\n\r\n", i+1, timestamp(cue.Start, ','), timestamp(cue.End, ','), strings.Join(lines(cue.Text), "\r\n"))
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes cues as a WebVTT file.
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		// a blank line or an arrow would end the cue early, so neither can appear in its text.
+		text := strings.ReplaceAll(strings.Join(lines(cue.Text), "\n"), "-->", "->")
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", timestamp(cue.Start, '.'), timestamp(cue.End, '.'), text)
+	}
+	return bw.Flush()
+}
+
+// WriteText writes the text of cues as plain text, one cue per line.
+func WriteText(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for _, cue := range cues {
+		bw.WriteString(strings.Join(lines(cue.Text), " "))
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// lines splits text into its non blank lines.
+func lines(text string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// timestamp formats ms as HH:MM:SS followed by sep and the milliseconds.
+func timestamp(ms int64, sep byte) string {
+	if ms < 0 {
+		ms = 0
+	}
+	secs := ms / 1000
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", secs/3600, secs/60%60, secs%60, sep, ms%1000)
+}
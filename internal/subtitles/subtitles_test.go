@@ -0,0 +1,79 @@
+package subtitles
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		cues []Cue
+		srt  string
+		vtt  string
+		text string
+	}{
+		{
+			name: "empty",
+			vtt:  "WEBVTT\n\n",
+		},
+		{
+			name: "single cue",
+			cues: []Cue{{Start: 1500, End: 4000, Text: "Hello."}},
+			srt:  "1\r\n00:00:01,500 --> 00:00:04,000\r\nHello.\r\n\r\n",
+			vtt:  "WEBVTT\n\n00:00:01.500 --> 00:00:04.000\nHello.\n\n",
+			text: "Hello.\n",
+		},
+		{
+			name: "numbered from one",
+			cues: []Cue{{Start: 0, End: 1000, Text: "One"}, {Start: 3723004, End: 3725000, Text: "Two"}},
+			srt:  "1\r\n00:00:00,000 --> 00:00:01,000\r\nOne\r\n\r\n2\r\n01:02:03,004 --> 01:02:05,000\r\nTwo\r\n\r\n",
+			vtt:  "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nOne\n\n01:02:03.004 --> 01:02:05.000\nTwo\n\n",
+			text: "One\nTwo\n",
+		},
+		{
+			// a blank line would end the cue, so blank lines are dropped.
+			name: "several lines",
+			cues: []Cue{{Start: 0, End: 2000, Text: "  First\r\n\r\nSecond \n"}},
+			srt:  "1\r\n00:00:00,000 --> 00:00:02,000\r\nFirst\r\nSecond\r\n\r\n",
+			vtt:  "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nFirst\nSecond\n\n",
+			text: "First Second\n",
+		},
+		{
+			name: "arrow in text",
+			cues: []Cue{{Start: 0, End: 1000, Text: "A --> B"}},
+			srt:  "1\r\n00:00:00,000 --> 00:00:01,000\r\nA --> B\r\n\r\n",
+			vtt:  "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nA -> B\n\n",
+			text: "A --> B\n",
+		},
+		{
+			name: "negative time",
+			cues: []Cue{{Start: -40, End: 1000, Text: "Early"}},
+			srt:  "1\r\n00:00:00,000 --> 00:00:01,000\r\nEarly\r\n\r\n",
+			vtt:  "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nEarly\n\n",
+			text: "Early\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, w := range []struct {
+				format string
+				write  func(*strings.Builder, []Cue) error
+				want   string
+			}{
+				{"srt", func(b *strings.Builder, cues []Cue) error { return WriteSRT(b, cues) }, tt.srt},
+				{"vtt", func(b *strings.Builder, cues []Cue) error { return WriteVTT(b, cues) }, tt.vtt},
+				{"text", func(b *strings.Builder, cues []Cue) error { return WriteText(b, cues) }, tt.text},
+			} {
+				var b strings.Builder
+				if err := w.write(&b, tt.cues); err != nil {
+					t.Fatal(err)
+				}
+				if b.String() != w.want {
+					t.Errorf("%s = %q, want %q", w.format, b.String(), w.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Package timecode converts between times in milliseconds and SMPTE timecodes.
+*/
+package timecode
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format formats ms as a non drop frame HH:MM:SS:FF timecode at fps frames per second.
+func Format(ms int64, fps float64) (string, error) {
+	nominal, err := nominalRate(fps)
+	if err != nil {
+		return "", err
+	}
+	frames := int64(math.Round(float64(ms) * fps / 1000))
+
+	ff := frames % nominal
+	secs := frames / nominal
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", secs/3600, secs/60%60, secs%60, ff), nil
+}
+
+// Parse parses a time as either a whole number of milliseconds, an HH:MM:SS:FF timecode at fps
+// frames per second, or HH:MM:SS.mmm. Drop frame timecodes separated by a semicolon are read
+// as non drop frame.
+func Parse(s string, fps float64) (int64, error) {
+	s = strings.TrimSpace(s)
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == ';' })
+	if len(parts) == 3 && strings.Contains(parts[2], ".") {
+		// HH:MM:SS.mmm
+		secs, err := strconv.ParseFloat(parts[2], 64)
+		h, errH := strconv.ParseInt(parts[0], 10, 64)
+		m, errM := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || errH != nil || errM != nil {
+			return 0, fmt.Errorf("invalid time %s", s)
+		}
+		return (h*3600+m*60)*1000 + int64(math.Round(secs*1000)), nil
+	}
+
+	if len(parts) != 4 {
+		return 0, fmt.Errorf("invalid time %s, expected milliseconds or HH:MM:SS:FF", s)
+	}
+
+	var n [4]int64
+	for i, p := range parts {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid timecode %s", s)
+		}
+		n[i] = v
+	}
+
+	nominal, err := nominalRate(fps)
+	if err != nil {
+		return 0, err
+	}
+	if n[1] > 59 || n[2] > 59 || n[3] >= nominal {
+		return 0, fmt.Errorf("invalid timecode %s at %v fps", s, fps)
+	}
+
+	frames := (n[0]*3600+n[1]*60+n[2])*nominal + n[3]
+	return int64(math.Round(float64(frames) * 1000 / fps)), nil
+}
+
+// nominalRate returns the whole number of frames a timecode counts each second at fps, which
+// must be at least 1.
+func nominalRate(fps float64) (int64, error) {
+	if !(fps >= 1) || math.IsInf(fps, 1) {
+		return 0, fmt.Errorf("invalid frame rate %v, timecodes need at least 1 frame a second", fps)
+	}
+	return int64(math.Round(fps)), nil
+}
@@ -0,0 +1,107 @@
+package timecode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		ms   int64
+		fps  float64
+		want string
+	}{
+		{0, 25, "00:00:00:00"},
+		{1000, 25, "00:00:01:00"},
+		{1040, 25, "00:00:01:01"},
+		{3723960, 25, "01:02:03:24"},
+		{59960, 30, "00:00:59:29"},
+		// times are rounded to the nearest frame.
+		{59999, 30, "00:01:00:00"},
+		// 29.97 counts 30 frames a second, so the timecode runs behind the clock.
+		{60000, 29.97, "00:00:59:28"},
+		{500, 23.976, "00:00:00:12"},
+		{1000, 50, "00:00:01:00"},
+		{36000000, 24, "10:00:00:00"},
+		// a frame rate below 1 counts no frames a second.
+		{1000, 0.4, ""},
+		{1000, 0, ""},
+		{1000, -25, ""},
+	}
+
+	for _, tt := range tests {
+		got, err := Format(tt.ms, tt.fps)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Format(%d, %v) = %s, want an error", tt.ms, tt.fps, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Format(%d, %v) = %s, %v, want %s", tt.ms, tt.fps, got, err, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		fps  float64
+		want int64
+		err  string
+	}{
+		{"0", 25, 0, ""},
+		{"90500", 25, 90500, ""},
+		{" 1500 ", 25, 1500, ""},
+		{"00:00:01:00", 25, 1000, ""},
+		{"00:00:01:01", 25, 1040, ""},
+		{"01:02:03:24", 25, 3723960, ""},
+		{"00:00:59;28", 29.97, 59993, ""},
+		{"00:01:30.5", 25, 90500, ""},
+		{"01:00:00.040", 25, 3600040, ""},
+		{"00:00:01", 25, 0, "invalid time 00:00:01, expected milliseconds or HH:MM:SS:FF"},
+		{"a:00:01:00", 25, 0, "invalid timecode a:00:01:00"},
+		{"00:00:-1:00", 25, 0, "invalid timecode 00:00:-1:00"},
+		{"00:00:01:25", 25, 0, "invalid timecode 00:00:01:25 at 25 fps"},
+		{"00:60:00:00", 25, 0, "invalid timecode 00:60:00:00 at 25 fps"},
+		{"00:xx:01.5", 25, 0, "invalid time 00:xx:01.5"},
+		{"00:00:01:00", 0.4, 0, "invalid frame rate 0.4"},
+		{"00:00:01:00", 0, 0, "invalid frame rate 0"},
+		// milliseconds need no frame rate.
+		{"1500", 0, 1500, ""},
+		{"", 25, 0, "invalid time , expected milliseconds or HH:MM:SS:FF"},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.s, tt.fps)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse(%q, %v) error = %v, want %q", tt.s, tt.fps, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q, %v) = %d, %v, want %d", tt.s, tt.fps, got, err, tt.want)
+		}
+	}
+}
+
+// TestRoundTrip checks the timecode of a whole number of frames reads back as the same timecode.
+func TestRoundTrip(t *testing.T) {
+	for _, fps := range []float64{23.976, 24, 25, 29.97, 30, 50, 59.94} {
+		for _, frames := range []int64{0, 1, 59, 3599, 90001} {
+			ms := int64(float64(frames) * 1000 / fps)
+			tc, err := Format(ms, fps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(tc, fps)
+			if err != nil {
+				t.Fatalf("Parse(%s, %v): %v", tc, fps, err)
+			}
+			if again, _ := Format(got, fps); again != tc {
+				t.Errorf("%d frames at %v fps: %s read back as %d, which formats as %s", frames, fps, tc, got, again)
+			}
+		}
+	}
+}
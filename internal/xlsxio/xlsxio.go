@@ -0,0 +1,396 @@
+/*
+Package xlsxio provides a reader and writer for Excel workbooks, exchanging rows
+in the same shape as the CSV readers and writers.
+*/
+package xlsxio
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// TypeText is a column whose cells are written as text.
+	TypeText = "text"
+	// TypeInteger is a column whose cells are written as whole numbers.
+	TypeInteger = "integer"
+	// TypeFloat is a column whose cells are written as decimal numbers.
+	TypeFloat = "float"
+	// TypeBoolean is a column whose cells are written as booleans.
+	TypeBoolean = "boolean"
+	// TypeDate is a column whose cells are written as dates.
+	TypeDate = "date"
+	// TypeDateTime is a column whose cells are written as date times, in the offset of their
+	// timezone.
+	TypeDateTime = "datetime"
+
+	// optionsSheet is the hidden sheet holding the drop-down choices of each column.
+	optionsSheet = "Options"
+	// maxRows is the last row of a worksheet, used so validation covers rows added later.
+	maxRows = 1048576
+
+	dateFormat     = "yyyy-mm-dd"
+	dateTimeFormat = `yyyy-mm-dd"T"hh:mm:ss"Z"`
+)
+
+// Column describes a column of a worksheet written by Writer.
+type Column struct {
+	Label   string
+	Type    string
+	Options []string
+}
+
+// ReadAll reads every non-empty row of a sheet as text, as it is displayed in Excel.
+// The sheet is selected by name or 1-based index, and defaults to the first sheet.
+// Rows are padded to the width of the header row.
+func ReadAll(r io.Reader, sheet string) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name, err := sheetName(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.GetRows(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var data [][]string
+	for i, row := range rows {
+		if isEmpty(row) {
+			continue
+		}
+		for j, val := range row {
+			if val != "TRUE" && val != "FALSE" {
+				continue
+			}
+			// Excel displays booleans in upper case, iconik expects them in lower case.
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return nil, err
+			}
+			if typ, err := f.GetCellType(name, cell); err == nil && typ == excelize.CellTypeBool {
+				row[j] = strings.ToLower(val)
+			}
+		}
+		data = append(data, row)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("sheet %s is empty", name)
+	}
+
+	width := len(data[0])
+	for i, row := range data {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		data[i] = row
+	}
+
+	return data, nil
+}
+
+// sheetName resolves a sheet flag value into the name of a sheet in the workbook.
+func sheetName(f *excelize.File, sheet string) (string, error) {
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("workbook has no sheets")
+	}
+
+	if sheet == "" {
+		return sheets[0], nil
+	}
+
+	for _, s := range sheets {
+		if s == sheet {
+			return s, nil
+		}
+	}
+
+	if i, err := strconv.Atoi(sheet); err == nil && i > 0 && i <= len(sheets) {
+		return sheets[i-1], nil
+	}
+
+	return "", fmt.Errorf("sheet %s not found, available sheets are: %s", sheet, strings.Join(sheets, ", "))
+}
+
+func isEmpty(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Writer writes rows to a single sheet workbook. The first HeaderRows rows written are
+// treated as header rows, and the rows after them are converted to typed cells according to
+// their Column. Nothing is written to the underlying io.Writer until Close is called.
+type Writer struct {
+	// HeaderRows is the number of header rows, which defaults to 1.
+	HeaderRows int
+
+	w       io.Writer
+	f       *excelize.File
+	sheet   string
+	columns []Column
+	row     int
+	styles  map[string]int
+}
+
+// NewWriter returns a new Writer that writes a workbook to w.
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+
+	styles := make(map[string]int)
+	for name, style := range map[string]*excelize.Style{
+		"header":     {Font: &excelize.Font{Bold: true}},
+		TypeDate:     {CustomNumFmt: strPtr(dateFormat)},
+		TypeDateTime: {CustomNumFmt: strPtr(dateTimeFormat)},
+		TypeText:     {NumFmt: 49, Alignment: &excelize.Alignment{WrapText: true}},
+	} {
+		id, err := f.NewStyle(style)
+		if err != nil {
+			return nil, err
+		}
+		styles[name] = id
+	}
+
+	// style whole columns too, so cells filled in later keep leading zeros and date formats.
+	for i, col := range columns {
+		style, ok := styles[col.Type]
+		if !ok {
+			continue
+		}
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err = f.SetColStyle(sheet, name, style); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Writer{
+		w:       w,
+		f:       f,
+		sheet:   sheet,
+		columns: columns,
+		styles:  styles,
+	}, nil
+}
+
+// WriteAll writes multiple rows to the sheet.
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes a single row to the sheet.
+func (w *Writer) Write(record []string) error {
+	w.row++
+
+	for i, val := range record {
+		cell, err := excelize.CoordinatesToCellName(i+1, w.row)
+		if err != nil {
+			return err
+		}
+
+		if w.row <= w.headerRows() {
+			if err = w.f.SetCellStr(w.sheet, cell, val); err != nil {
+				return err
+			}
+			if err = w.f.SetCellStyle(w.sheet, cell, cell, w.styles["header"]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = w.setCell(cell, w.columnType(i), val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) headerRows() int {
+	if w.HeaderRows < 1 {
+		return 1
+	}
+	return w.HeaderRows
+}
+
+func (w *Writer) columnType(i int) string {
+	if i < len(w.columns) {
+		return w.columns[i].Type
+	}
+	return TypeText
+}
+
+// setCell writes val to the cell as the given type, falling back to text when val does
+// not parse as that type so no data is lost.
+func (w *Writer) setCell(cell, typ, val string) error {
+	if val == "" {
+		return nil
+	}
+
+	switch typ {
+	case TypeInteger:
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return w.f.SetCellInt(w.sheet, cell, int(n))
+		}
+	case TypeFloat:
+		if n, err := strconv.ParseFloat(val, 64); err == nil {
+			return w.f.SetCellFloat(w.sheet, cell, n, -1, 64)
+		}
+	case TypeBoolean:
+		if b, err := strconv.ParseBool(val); err == nil {
+			return w.f.SetCellBool(w.sheet, cell, b)
+		}
+	case TypeDate:
+		if t, err := time.Parse(time.DateOnly, val); err == nil {
+			return w.setTime(cell, t, typ)
+		}
+	case TypeDateTime:
+		if t, err := time.Parse(time.RFC3339, val); err == nil {
+			return w.setDateTime(cell, t)
+		}
+	}
+
+	if err := w.f.SetCellStr(w.sheet, cell, val); err != nil {
+		return err
+	}
+	return w.f.SetCellStyle(w.sheet, cell, cell, w.styles[TypeText])
+}
+
+func (w *Writer) setTime(cell string, t time.Time, typ string) error {
+	if err := w.f.SetCellValue(w.sheet, cell, t); err != nil {
+		return err
+	}
+	return w.f.SetCellStyle(w.sheet, cell, cell, w.styles[typ])
+}
+
+// setDateTime writes t as the wall clock time of its offset, formatted with that offset so the
+// cell reads back as the same instant. Styles for offsets other than UTC are added as they are
+// first met, as a column can hold several, such as either side of a daylight saving change.
+func (w *Writer) setDateTime(cell string, t time.Time) error {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return w.setTime(cell, t, TypeDateTime)
+	}
+
+	zone := t.Format("-07:00")
+	key := TypeDateTime + zone
+	if _, ok := w.styles[key]; !ok {
+		// each character of the offset is escaped, as not every reader keeps the digits of a quoted literal.
+		format := strings.TrimSuffix(dateTimeFormat, `"Z"`)
+		for _, c := range zone {
+			format += `\` + string(c)
+		}
+		id, err := w.f.NewStyle(&excelize.Style{CustomNumFmt: &format})
+		if err != nil {
+			return err
+		}
+		w.styles[key] = id
+	}
+
+	return w.setTime(cell, t, key)
+}
+
+// Close adds the drop-down validations and frozen header rows, then writes the workbook.
+func (w *Writer) Close() error {
+	defer w.f.Close()
+
+	if err := w.addDropDowns(); err != nil {
+		return err
+	}
+
+	if err := w.f.SetPanes(w.sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      w.headerRows(),
+		TopLeftCell: fmt.Sprintf("A%d", w.headerRows()+1),
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+
+	if len(w.columns) > 0 {
+		last, err := excelize.ColumnNumberToName(len(w.columns))
+		if err != nil {
+			return err
+		}
+		if err = w.f.SetColWidth(w.sheet, "A", last, 20); err != nil {
+			return err
+		}
+	}
+
+	return w.f.Write(w.w)
+}
+
+// addDropDowns writes each column's options to a hidden sheet, and adds a list
+// validation referencing them to the column. Values outside the list raise a warning
+// rather than being rejected, so multi-value cells can still be entered.
+func (w *Writer) addDropDowns() error {
+	created := false
+	for i, col := range w.columns {
+		if len(col.Options) == 0 {
+			continue
+		}
+
+		if !created {
+			if _, err := w.f.NewSheet(optionsSheet); err != nil {
+				return err
+			}
+			if err := w.f.SetSheetVisible(optionsSheet, false); err != nil {
+				return err
+			}
+			created = true
+		}
+
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+
+		for j, opt := range col.Options {
+			if err = w.f.SetCellStr(optionsSheet, fmt.Sprintf("%s%d", name, j+1), opt); err != nil {
+				return err
+			}
+		}
+
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = fmt.Sprintf("%s%d:%s%d", name, w.headerRows()+1, name, maxRows)
+		dv.SetSqrefDropList(fmt.Sprintf("%s!$%s$1:$%s$%d", optionsSheet, name, name, len(col.Options)))
+		dv.SetError(excelize.DataValidationErrorStyleWarning, col.Label, "This value is not one of the options for this field.")
+		if err = w.f.AddDataValidation(w.sheet, dv); err != nil {
+			return err
+		}
+	}
+
+	if created {
+		w.f.SetActiveSheet(0)
+	}
+
+	return nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
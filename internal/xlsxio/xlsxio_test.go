@@ -0,0 +1,161 @@
+package xlsxio
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// TestRoundTrip writes a value to a column of each type and reads it back as it is displayed.
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   string
+		value string
+		want  string
+	}{
+		{"text", TypeText, "Smith, John", "Smith, John"},
+		{"leading zeros", TypeText, "007", "007"},
+		{"number as text", TypeText, "1e3", "1e3"},
+		{"integer", TypeInteger, "42", "42"},
+		{"negative integer", TypeInteger, "-7", "-7"},
+		{"float", TypeFloat, "3.25", "3.25"},
+		{"boolean true", TypeBoolean, "true", "true"},
+		{"boolean false", TypeBoolean, "false", "false"},
+		{"date", TypeDate, "2024-03-01", "2024-03-01"},
+		{"datetime utc", TypeDateTime, "2024-03-01T10:30:00Z", "2024-03-01T10:30:00Z"},
+		{"datetime offset", TypeDateTime, "2024-03-01T10:30:00+01:00", "2024-03-01T10:30:00+01:00"},
+		{"datetime negative offset", TypeDateTime, "2024-03-01T23:59:59-05:30", "2024-03-01T23:59:59-05:30"},
+		// values which don't parse as their type are kept as text.
+		{"integer as text", TypeInteger, "12a", "12a"},
+		{"float as text", TypeFloat, "n/a", "n/a"},
+		{"boolean as text", TypeBoolean, "yes", "yes"},
+		{"date as text", TypeDate, "01/03/2024", "01/03/2024"},
+		{"datetime as text", TypeDateTime, "2024-03-01 10:30", "2024-03-01 10:30"},
+		{"empty", TypeInteger, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := NewWriter(&b, []Column{{Label: "ID"}, {Label: "Value", Type: tt.typ}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = w.WriteAll([][]string{{"ID", "Value"}, {"1", tt.value}}); err != nil {
+				t.Fatal(err)
+			}
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadAll(&b, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{{"ID", "Value"}, {"1", tt.want}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadAll() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriterHeaderRows(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewWriter(&b, []Column{{Label: "Count", Type: TypeInteger, Options: []string{"1", "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.HeaderRows = 2
+	// the second header row holds field names, which stay text in a typed column.
+	if err = w.WriteAll([][]string{{"Count"}, {"count"}, {"2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// excelize leaves the type of a number cell unset.
+	for cell, want := range map[string]excelize.CellType{"A2": excelize.CellTypeSharedString, "A3": excelize.CellTypeUnset} {
+		if typ, err := f.GetCellType("Sheet1", cell); err != nil || typ != want {
+			t.Errorf("%s type = %v, %v, want %v", cell, typ, err, want)
+		}
+	}
+	if visible, err := f.GetSheetVisible(optionsSheet); err != nil || visible {
+		t.Errorf("%s sheet visible = %v, %v, want hidden", optionsSheet, visible, err)
+	}
+	dvs, err := f.GetDataValidations("Sheet1")
+	if err != nil || len(dvs) != 1 || dvs[0].Sqref != "A3:A1048576" {
+		t.Errorf("data validations = %v, %v, want one from A3", dvs, err)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	workbook := func(t *testing.T) *bytes.Buffer {
+		t.Helper()
+		f := excelize.NewFile()
+		defer f.Close()
+		if _, err := f.NewSheet("Assets"); err != nil {
+			t.Fatal(err)
+		}
+		for cell, value := range map[string]string{
+			"A1": "id", "B1": "title", "C1": "notes",
+			"A2": "1", "B2": "Clip 1",
+			// an empty row between rows is skipped.
+			"A4": "2", "B4": "Clip 2", "C4": "Note",
+		} {
+			if err := f.SetCellStr("Assets", cell, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.SetCellStr("Sheet1", "A1", "other"); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := f.Write(&b); err != nil {
+			t.Fatal(err)
+		}
+		return &b
+	}
+	assets := [][]string{{"id", "title", "notes"}, {"1", "Clip 1", ""}, {"2", "Clip 2", "Note"}}
+
+	tests := []struct {
+		sheet string
+		want  [][]string
+		err   string
+	}{
+		{"", [][]string{{"other"}}, ""},
+		{"Assets", assets, ""},
+		{"2", assets, ""},
+		{"1", [][]string{{"other"}}, ""},
+		{"3", nil, "sheet 3 not found, available sheets are: Sheet1, Assets"},
+		{"Clips", nil, "sheet Clips not found, available sheets are: Sheet1, Assets"},
+	}
+
+	for _, tt := range tests {
+		t.Run("sheet "+tt.sheet, func(t *testing.T) {
+			got, err := ReadAll(workbook(t), tt.sheet)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ReadAll() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package xmlprofile
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatISO8601 formats a duration in milliseconds as an ISO 8601 duration, such as PT1M30.5S.
+	FormatISO8601 = "iso8601"
+	// FormatTimecode formats a duration in milliseconds as HH:MM:SS.mmm.
+	FormatTimecode = "timecode"
+	// FormatDate formats an RFC 3339 date time as a date, such as 2024-01-31.
+	FormatDate = "date"
+)
+
+// Resolver returns the values of a source for a single asset.
+type Resolver func(source string) []string
+
+// Node is an element of a document.
+type Node struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*Node
+}
+
+// Build returns a document holding the elements of a single asset.
+func (p Profile) Build(resolve Resolver) *Node {
+	root, inner := chain(p.Document)
+	p.fill(inner, resolve)
+	return root
+}
+
+// Collection is a document holding the elements of every asset in a collection.
+type Collection struct {
+	profile Profile
+	root    *Node
+	inner   *Node
+}
+
+// NewCollection returns a new collection document with the given title.
+func (p Profile) NewCollection(title string) (*Collection, error) {
+	if p.Collection.Root == "" || p.Collection.Item == "" {
+		return nil, fmt.Errorf("profile %s does not support collection documents", p.Name)
+	}
+
+	root, inner := chain(p.Collection.Root)
+	if p.Collection.Title != "" {
+		set(inner, p.Collection.Title, title, nil)
+	}
+
+	return &Collection{profile: p, root: root, inner: inner}, nil
+}
+
+// Add adds the elements of an asset to the collection document.
+func (c *Collection) Add(resolve Resolver) {
+	item, inner := chain(c.profile.Collection.Item)
+	c.inner.Children = append(c.inner.Children, item)
+	c.profile.fill(inner, resolve)
+}
+
+// Root returns the root element of the collection document.
+func (c *Collection) Root() *Node {
+	return c.root
+}
+
+// chain returns the first and last of a chain of nested elements built from a path.
+func chain(path string) (*Node, *Node) {
+	var root, inner *Node
+	for _, name := range strings.Split(path, "/") {
+		n := &Node{Name: name}
+		if root == nil {
+			root = n
+		} else {
+			inner.Children = append(inner.Children, n)
+		}
+		inner = n
+	}
+	return root, inner
+}
+
+// fill adds the profile's elements to parent. Consecutive elements share their parent
+// elements, while each further value of a multi-value source repeats the whole path.
+func (p Profile) fill(parent *Node, resolve Resolver) {
+	for _, el := range p.Elements {
+		var values []string
+		if el.Source != "" {
+			for _, v := range resolve(el.Source) {
+				if v = format(v, el.Format); v != "" {
+					values = append(values, v)
+				}
+			}
+		}
+		if len(values) == 0 && el.Value != "" {
+			values = []string{el.Value}
+		}
+		if len(values) == 0 {
+			if !el.Required {
+				continue
+			}
+			values = []string{""}
+		}
+
+		segments := strings.Split(el.Path, "/")
+		for i, v := range values {
+			if i > 0 && len(segments) > 1 {
+				parent.Children = append(parent.Children, &Node{Name: segments[0]})
+			}
+			set(parent, el.Path, v, el.Attributes)
+		}
+	}
+}
+
+// set writes a value to the element at path below parent, reusing the last child of each
+// level when it has the same name. A final segment starting with @ sets an attribute.
+func set(parent *Node, path, value string, attrs map[string]string) {
+	segments := strings.Split(path, "/")
+	last := segments[len(segments)-1]
+
+	n := parent
+	for _, name := range segments[:len(segments)-1] {
+		if len(n.Children) > 0 && n.Children[len(n.Children)-1].Name == name {
+			n = n.Children[len(n.Children)-1]
+			continue
+		}
+		child := &Node{Name: name}
+		n.Children = append(n.Children, child)
+		n = child
+	}
+
+	if strings.HasPrefix(last, "@") {
+		if n.Attrs == nil {
+			n.Attrs = make(map[string]string)
+		}
+		n.Attrs[strings.TrimPrefix(last, "@")] = value
+		return
+	}
+
+	leaf := &Node{Name: last, Text: value, Attrs: make(map[string]string, len(attrs))}
+	for k, v := range attrs {
+		leaf.Attrs[k] = v
+	}
+	n.Children = append(n.Children, leaf)
+}
+
+// format converts a value to the given format, returning it unchanged if it can't be converted.
+func format(v, f string) string {
+	switch f {
+	case FormatISO8601, FormatTimecode:
+		ms, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return v
+		}
+		d := time.Duration(ms * float64(time.Millisecond))
+		h, m := int(d.Hours()), int(d.Minutes())%60
+		s := d.Seconds() - float64(h*3600+m*60)
+		if f == FormatTimecode {
+			return fmt.Sprintf("%02d:%02d:%06.3f", h, m, s)
+		}
+		return fmt.Sprintf("PT%dH%dM%sS", h, m, strconv.FormatFloat(s, 'f', -1, 64))
+	case FormatDate:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return v
+		}
+		return t.Format(time.DateOnly)
+	}
+	return v
+}
+
+// Write writes the document to w, declaring the profile's namespaces on the root element.
+func (p Profile) Write(w io.Writer, root *Node) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+
+	attrs := make(map[string]string, len(root.Attrs)+len(p.Namespaces))
+	for k, v := range root.Attrs {
+		attrs[k] = v
+	}
+	for prefix, uri := range p.Namespaces {
+		if prefix == "" {
+			attrs["xmlns"] = uri
+			continue
+		}
+		attrs["xmlns:"+prefix] = uri
+	}
+
+	writeNode(bw, &Node{Name: root.Name, Attrs: attrs, Text: root.Text, Children: root.Children}, 0)
+
+	return bw.Flush()
+}
+
+func writeNode(bw *bufio.Writer, n *Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	bw.WriteString(indent + "<" + n.Name)
+
+	keys := make([]string, 0, len(n.Attrs))
+	for k := range n.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		bw.WriteString(" " + k + `="`)
+		xml.EscapeText(bw, []byte(n.Attrs[k]))
+		bw.WriteString(`"`)
+	}
+
+	switch {
+	case len(n.Children) > 0:
+		bw.WriteString(">\n")
+		for _, c := range n.Children {
+			writeNode(bw, c, depth+1)
+		}
+		bw.WriteString(indent + "</" + n.Name + ">\n")
+	case n.Text != "":
+		bw.WriteString(">")
+		xml.EscapeText(bw, []byte(n.Text))
+		bw.WriteString("</" + n.Name + ">\n")
+	default:
+		bw.WriteString("/>\n")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Package xmlprofile writes XML documents, such as EBUCore and PBCore, from profiles which
+map asset properties, file info and view fields onto the elements of a standard, and
+validates them against a bundled schema.
+*/
+package xmlprofile
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//go:embed profiles/*.json schemas/*.xsd
+var bundled embed.FS
+
+// Profile maps values onto the elements of an XML standard.
+type Profile struct {
+	Name string `json:"name"`
+	// Namespaces maps the prefixes used in element paths to namespace URIs. The empty prefix
+	// is the default namespace.
+	Namespaces map[string]string `json:"namespaces"`
+	// Schema is the name of a bundled schema, or the path of a schema file, used for validation.
+	Schema string `json:"schema"`
+	// Document is the path of the element which holds the elements of a single asset.
+	Document string `json:"document"`
+	// Collection describes the document written for a whole collection.
+	Collection struct {
+		// Root is the path of the element which holds the title and items of the collection.
+		Root string `json:"root"`
+		// Title is the path, relative to Root, the collection title is written to. A final
+		// segment starting with @ is written as an attribute of Root.
+		Title string `json:"title"`
+		// Item is the element which holds the elements of each asset within Root.
+		Item string `json:"item"`
+	} `json:"collection"`
+	Elements []Element `json:"elements"`
+}
+
+// Element maps a source value onto an element.
+type Element struct {
+	// Path is the path of the element relative to the document, separated by slashes.
+	Path string `json:"path"`
+	// Source names the value written to the element, such as asset.title, file.size or
+	// field.<name>. An element is written for each value of a multi-value source.
+	Source string `json:"source"`
+	// Value is a literal value, written when the source is empty or has no values.
+	Value string `json:"value"`
+	// Format converts the value, either iso8601 or timecode for durations in milliseconds, or
+	// date for date times.
+	Format string `json:"format"`
+	// Attributes are literal attributes added to the element.
+	Attributes map[string]string `json:"attributes"`
+	// Required writes the element empty when it has no value, rather than leaving it out.
+	Required bool `json:"required"`
+}
+
+// Load returns the bundled profile with the given name, or reads a profile from a JSON file.
+func Load(nameOrPath string) (Profile, error) {
+	b, err := bundled.ReadFile("profiles/" + strings.ToLower(nameOrPath) + ".json")
+	if err != nil {
+		b, err = os.ReadFile(nameOrPath)
+		if err != nil {
+			return Profile{}, fmt.Errorf("profile %s is not bundled and could not be read: %w", nameOrPath, err)
+		}
+	}
+
+	var p Profile
+	if err = json.Unmarshal(b, &p); err != nil {
+		return Profile{}, fmt.Errorf("invalid profile %s: %w", nameOrPath, err)
+	}
+
+	if p.Document == "" {
+		return Profile{}, fmt.Errorf("profile %s has no document element", nameOrPath)
+	}
+
+	return p, nil
+}
+
+// LoadSchema returns the schema the profile's documents are validated against, if it has one.
+func (p Profile) LoadSchema() (*Schema, error) {
+	if p.Schema == "" {
+		return nil, nil
+	}
+
+	b, err := bundled.ReadFile("schemas/" + p.Schema)
+	if err != nil {
+		b, err = os.ReadFile(p.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s is not bundled and could not be read: %w", p.Schema, err)
+		}
+	}
+
+	return ParseSchema(b)
+}
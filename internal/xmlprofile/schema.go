@@ -0,0 +1,402 @@
+package xmlprofile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Schema is the subset of an XML schema needed to validate profile documents: global
+// elements, named and anonymous complex types, sequences and choices with occurrence
+// limits, element references, and required attributes. Elements are matched by local
+// name, and references to elements outside the schema, such as dc:title, accept any content.
+type Schema struct {
+	elements map[string]*elementDecl
+	types    map[string]*complexType
+}
+
+type elementDecl struct {
+	name     string
+	ref      string
+	typeName string
+	complex  *complexType
+	min, max int
+}
+
+type complexType struct {
+	group *group
+	attrs []attrDecl
+	// simple is true for types holding text, with or without attributes.
+	simple bool
+}
+
+type group struct {
+	choice    bool
+	particles []particle
+	min, max  int
+}
+
+type particle struct {
+	element *elementDecl
+	group   *group
+}
+
+type attrDecl struct {
+	name     string
+	required bool
+}
+
+// unbounded is the max of particles which may occur any number of times.
+const unbounded = -1
+
+// xsdNode is an element of a parsed schema document.
+type xsdNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xsdNode  `xml:",any"`
+}
+
+func (n xsdNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// ParseSchema parses an XML schema document.
+func ParseSchema(b []byte) (*Schema, error) {
+	var root xsdNode
+	if err := xml.NewDecoder(bytes.NewReader(b)).Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if root.XMLName.Space != xsdNamespace || root.XMLName.Local != "schema" {
+		return nil, errors.New("invalid schema: root element is not xs:schema")
+	}
+
+	s := &Schema{
+		elements: make(map[string]*elementDecl),
+		types:    make(map[string]*complexType),
+	}
+
+	for _, c := range root.Children {
+		switch c.XMLName.Local {
+		case "element":
+			el, err := parseElement(c)
+			if err != nil {
+				return nil, err
+			}
+			s.elements[el.name] = el
+		case "complexType":
+			ct, err := parseComplexType(c)
+			if err != nil {
+				return nil, err
+			}
+			s.types[c.attr("name")] = ct
+		}
+	}
+
+	return s, nil
+}
+
+func parseElement(n xsdNode) (*elementDecl, error) {
+	min, max, err := occurs(n)
+	if err != nil {
+		return nil, err
+	}
+
+	el := &elementDecl{
+		name:     n.attr("name"),
+		ref:      localName(n.attr("ref")),
+		typeName: n.attr("type"),
+		min:      min,
+		max:      max,
+	}
+
+	for _, c := range n.Children {
+		if c.XMLName.Local == "complexType" {
+			if el.complex, err = parseComplexType(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return el, nil
+}
+
+func parseComplexType(n xsdNode) (*complexType, error) {
+	ct := &complexType{}
+	for _, c := range n.Children {
+		switch c.XMLName.Local {
+		case "sequence", "choice":
+			g, err := parseGroup(c)
+			if err != nil {
+				return nil, err
+			}
+			ct.group = g
+		case "attribute":
+			ct.attrs = append(ct.attrs, attrDecl{name: c.attr("name"), required: c.attr("use") == "required"})
+		case "simpleContent":
+			ct.simple = true
+			for _, ext := range c.Children {
+				for _, a := range ext.Children {
+					if a.XMLName.Local == "attribute" {
+						ct.attrs = append(ct.attrs, attrDecl{name: a.attr("name"), required: a.attr("use") == "required"})
+					}
+				}
+			}
+		}
+	}
+	if n.attr("mixed") == "true" {
+		ct.simple = true
+	}
+
+	return ct, nil
+}
+
+func parseGroup(n xsdNode) (*group, error) {
+	min, max, err := occurs(n)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &group{choice: n.XMLName.Local == "choice", min: min, max: max}
+	for _, c := range n.Children {
+		switch c.XMLName.Local {
+		case "element":
+			el, err := parseElement(c)
+			if err != nil {
+				return nil, err
+			}
+			g.particles = append(g.particles, particle{element: el})
+		case "sequence", "choice":
+			inner, err := parseGroup(c)
+			if err != nil {
+				return nil, err
+			}
+			g.particles = append(g.particles, particle{group: inner})
+		}
+	}
+
+	return g, nil
+}
+
+func occurs(n xsdNode) (int, int, error) {
+	min, max := 1, 1
+	var err error
+	if v := n.attr("minOccurs"); v != "" {
+		if min, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid schema: minOccurs %s", v)
+		}
+	}
+	switch v := n.attr("maxOccurs"); v {
+	case "":
+	case "unbounded":
+		max = unbounded
+	default:
+		if max, err = strconv.Atoi(v); err != nil {
+			return 0, 0, fmt.Errorf("invalid schema: maxOccurs %s", v)
+		}
+	}
+	return min, max, nil
+}
+
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// Validate checks a document against the schema, returning every problem found.
+func (s *Schema) Validate(root *Node) []error {
+	el, ok := s.elements[localName(root.Name)]
+	if !ok {
+		return []error{fmt.Errorf("%s is not a root element of the schema", root.Name)}
+	}
+
+	v := &validator{schema: s}
+	v.element(root, el, "/"+root.Name)
+	return v.errs
+}
+
+type validator struct {
+	schema  *Schema
+	errs    []error
+	missing string
+	found   string
+}
+
+// expected records the element a group expected at index i of children, and the element found there.
+func (v *validator) expected(children []*Node, i int, name string) {
+	v.missing, v.found = name, ""
+	if i < len(children) {
+		v.found = children[i].Name
+	}
+}
+
+func (v *validator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// element validates a node against its declaration.
+func (v *validator) element(n *Node, el *elementDecl, path string) {
+	if el.ref != "" {
+		global, ok := v.schema.elements[el.ref]
+		if !ok {
+			// references to other schemas, such as Dublin Core, accept any content.
+			return
+		}
+		el = global
+	}
+
+	ct := el.complex
+	if ct == nil {
+		switch {
+		case el.typeName == "" || strings.HasSuffix(el.typeName, ":anyType"):
+			return
+		case v.schema.types[localName(el.typeName)] != nil:
+			ct = v.schema.types[localName(el.typeName)]
+		default:
+			// simple types hold text only.
+			if len(n.Children) > 0 {
+				v.errorf("%s: element can only hold text", path)
+			}
+			return
+		}
+	}
+
+	for _, a := range ct.attrs {
+		if _, ok := n.Attrs[a.name]; a.required && !ok {
+			v.errorf("%s: missing required attribute %s", path, a.name)
+		}
+	}
+
+	if ct.group == nil {
+		if len(n.Children) > 0 {
+			v.errorf("%s: element can only hold text", path)
+		}
+		return
+	}
+
+	if n.Text != "" && !ct.simple {
+		v.errorf("%s: element can't hold text", path)
+	}
+
+	i, ok := v.group(n.Children, 0, ct.group, path)
+	switch {
+	case !ok && v.found != "":
+		v.errorf("%s: expected element %s, found %s", path, v.missing, v.found)
+	case !ok:
+		v.errorf("%s: missing required element %s", path, v.missing)
+	case i < len(n.Children):
+		v.errorf("%s: unexpected element %s", path, n.Children[i].Name)
+	}
+}
+
+// group matches children from index i against a group, returning the index after the last
+// child matched and whether the group's minimum occurrences were met.
+func (v *validator) group(children []*Node, i int, g *group, path string) (int, bool) {
+	count := 0
+	for g.max == unbounded || count < g.max {
+		j, ok := v.groupOnce(children, i, g, path)
+		if !ok {
+			break
+		}
+		if j == i {
+			// a group whose particles are all optional is satisfied by no elements.
+			return i, true
+		}
+		i = j
+		count++
+	}
+	return i, count >= g.min
+}
+
+// groupOnce matches a single occurrence of a group, recording the element it expected when
+// it doesn't match.
+func (v *validator) groupOnce(children []*Node, i int, g *group, path string) (int, bool) {
+	if g.choice {
+		for _, p := range g.particles {
+			if j, ok := v.particle(children, i, p, path); ok && j > i {
+				return j, true
+			}
+		}
+		for _, p := range g.particles {
+			if optional(p) {
+				return i, true
+			}
+		}
+		v.expected(children, i, firstName(g))
+		return i, false
+	}
+
+	start := i
+	for _, p := range g.particles {
+		j, ok := v.particle(children, i, p, path)
+		if !ok {
+			v.expected(children, i, particleName(p))
+			return start, false
+		}
+		i = j
+	}
+	return i, true
+}
+
+// particle matches children from index i against a particle.
+func (v *validator) particle(children []*Node, i int, p particle, path string) (int, bool) {
+	if p.group != nil {
+		return v.group(children, i, p.group, path)
+	}
+
+	el := p.element
+	name := el.name
+	if el.ref != "" {
+		name = el.ref
+	}
+
+	count := 0
+	for i < len(children) && (el.max == unbounded || count < el.max) && localName(children[i].Name) == name {
+		v.element(children[i], el, path+"/"+children[i].Name)
+		i++
+		count++
+	}
+	return i, count >= el.min
+}
+
+func optional(p particle) bool {
+	if p.group != nil {
+		return p.group.min == 0
+	}
+	return p.element.min == 0
+}
+
+func particleName(p particle) string {
+	if p.group != nil {
+		return firstName(p.group)
+	}
+	if p.element.ref != "" {
+		return p.element.ref
+	}
+	return p.element.name
+}
+
+func firstName(g *group) string {
+	names := make([]string, 0, len(g.particles))
+	for _, p := range g.particles {
+		names = append(names, particleName(p))
+	}
+	if g.choice {
+		return strings.Join(names, " or ")
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,149 @@
+package xmlprofile
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:test" targetNamespace="urn:test">
+  <xs:element name="clip">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="title" type="t:titleType" maxOccurs="2"/>
+        <xs:choice minOccurs="0">
+          <xs:element name="duration" type="xs:string"/>
+          <xs:element name="frames" type="xs:integer"/>
+        </xs:choice>
+        <xs:element name="note" type="xs:anyType" minOccurs="0" maxOccurs="unbounded"/>
+      </xs:sequence>
+      <xs:attribute name="id" use="required"/>
+    </xs:complexType>
+  </xs:element>
+
+  <xs:complexType name="titleType">
+    <xs:simpleContent>
+      <xs:extension base="xs:string">
+        <xs:attribute name="lang" use="required"/>
+      </xs:extension>
+    </xs:simpleContent>
+  </xs:complexType>
+</xs:schema>`
+
+// el returns a node named name holding children.
+func el(name string, children ...*Node) *Node {
+	return &Node{Name: name, Children: children}
+}
+
+// title returns a valid title node.
+func title(text string) *Node {
+	return &Node{Name: "t:title", Text: text, Attrs: map[string]string{"lang": "en"}}
+}
+
+// clip returns a clip node with a valid id.
+func clip(children ...*Node) *Node {
+	n := el("t:clip", children...)
+	n.Attrs = map[string]string{"id": "1"}
+	return n
+}
+
+func TestValidate(t *testing.T) {
+	s, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  *Node
+		// errs are the problems expected, each matched as a substring. No problems are
+		// expected of a valid document.
+		errs []string
+	}{
+		{"minimal", clip(title("A")), nil},
+		{"every element", clip(title("A"), title("B"), &Node{Name: "t:frames", Text: "25"}, el("t:note", el("anything")), el("t:note")), nil},
+		{"other choice", clip(title("A"), &Node{Name: "t:duration", Text: "PT1S"}), nil},
+		{"wrong root", el("t:asset"), []string{"t:asset is not a root element"}},
+		{"missing required element", clip(), []string{"/t:clip: missing required element title"}},
+		{"missing required attribute", el("t:clip", title("A")), []string{"/t:clip: missing required attribute id"}},
+		{"missing attribute of simple content", clip(&Node{Name: "t:title", Text: "A"}), []string{"/t:clip/t:title: missing required attribute lang"}},
+		{"wrong order", clip(el("t:note"), title("A")), []string{"/t:clip: expected element title, found t:note"}},
+		{"too many", clip(title("A"), title("B"), title("C")), []string{"/t:clip: unexpected element t:title"}},
+		{"both choices", clip(title("A"), &Node{Name: "t:frames", Text: "1"}, &Node{Name: "t:duration", Text: "PT1S"}), []string{"unexpected element t:duration"}},
+		{"unknown element", clip(title("A"), el("t:rating")), []string{"/t:clip: unexpected element t:rating"}},
+		{"text in element only", &Node{Name: "t:clip", Text: "x", Attrs: map[string]string{"id": "1"}, Children: []*Node{title("A")}}, []string{"/t:clip: element can't hold text"}},
+		{"children in text only", clip(title("A"), el("t:frames", el("t:n"))), []string{"/t:clip/t:frames: element can only hold text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := s.Validate(tt.doc)
+			if len(errs) != len(tt.errs) {
+				t.Fatalf("Validate() = %v, want %d problems", errs, len(tt.errs))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tt.errs[i]) {
+					t.Errorf("Validate() problem %d = %q, want %q", i, err, tt.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBundled(t *testing.T) {
+	values := map[string][]string{
+		"asset.title":        {"Asset 1"},
+		"asset.id":           {"00000000-0000-0000-0000-000000000001"},
+		"asset.date_created": {"2024-03-01T10:00:00Z"},
+		"asset.format":       {"ORIGINAL"},
+		"asset.duration":     {"90500"},
+		"field.description":  {"A description"},
+		"file.size":          {"1000"},
+		"file.original_name": {"clip1.mov"},
+	}
+	resolve := func(source string) []string { return values[source] }
+
+	for _, name := range []string{"ebucore", "pbcore"} {
+		t.Run(name, func(t *testing.T) {
+			p, err := Load(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := p.LoadSchema()
+			if err != nil || s == nil {
+				t.Fatalf("LoadSchema() = %v, %v", s, err)
+			}
+
+			doc := p.Build(resolve)
+			if errs := s.Validate(doc); len(errs) > 0 {
+				t.Errorf("asset document: %v", errs)
+			}
+
+			c, err := p.NewCollection("Rushes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Add(resolve)
+			c.Add(resolve)
+			if errs := s.Validate(c.Root()); len(errs) > 0 {
+				t.Errorf("collection document: %v", errs)
+			}
+
+			// the document element holds its children in the order the schema requires, so
+			// reversing them leaves it invalid.
+			inner := doc
+			for len(inner.Children) == 1 {
+				inner = inner.Children[0]
+			}
+			if len(inner.Children) < 2 {
+				t.Fatalf("%s document has too few elements to reorder", name)
+			}
+			for i, j := 0, len(inner.Children)-1; i < j; i, j = i+1, j-1 {
+				inner.Children[i], inner.Children[j] = inner.Children[j], inner.Children[i]
+			}
+			if errs := s.Validate(doc); len(errs) == 0 {
+				t.Error("reordered document is valid")
+			}
+		})
+	}
+}
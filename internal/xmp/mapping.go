@@ -0,0 +1,108 @@
+package xmp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultPrefix is the prefix of the namespace view fields are written to when they aren't mapped.
+	DefaultPrefix = "iconik"
+	// DefaultNamespace is the namespace view fields are written to when they aren't mapped.
+	DefaultNamespace = "http://ns.iconik.io/metadata/1.0/"
+
+	// PropertyID is the custom property holding the iconik ID of the asset.
+	PropertyID = "id"
+	// PropertyOriginalName is the custom property holding the original filename of the asset.
+	PropertyOriginalName = "original_name"
+)
+
+// Mapping maps view field names to XMP properties. Fields which aren't mapped are written
+// as properties of the same name in the custom namespace.
+type Mapping struct {
+	Namespace struct {
+		Prefix string `json:"prefix"`
+		URI    string `json:"uri"`
+	} `json:"namespace"`
+	// Fields maps view field names to prefixed property names, such as dc:description.
+	Fields map[string]string `json:"fields"`
+}
+
+// LoadMapping reads a mapping from a JSON file. An empty path returns the default mapping,
+// which writes every field to the custom namespace.
+func LoadMapping(path string) (Mapping, error) {
+	var m Mapping
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return Mapping{}, err
+		}
+		if err = json.Unmarshal(b, &m); err != nil {
+			return Mapping{}, fmt.Errorf("invalid XMP mapping file: %w", err)
+		}
+	}
+
+	if m.Namespace.Prefix == "" {
+		m.Namespace.Prefix = DefaultPrefix
+	}
+	if m.Namespace.URI == "" {
+		m.Namespace.URI = DefaultNamespace
+	}
+
+	for field, qname := range m.Fields {
+		prefix, _, ok := strings.Cut(qname, ":")
+		if !ok {
+			return Mapping{}, fmt.Errorf("XMP property %s for field %s has no namespace prefix", qname, field)
+		}
+		if _, ok = Namespaces[prefix]; !ok && prefix != m.Namespace.Prefix {
+			return Mapping{}, fmt.Errorf("XMP property %s for field %s has an unknown namespace prefix", qname, field)
+		}
+	}
+
+	return m, nil
+}
+
+// Custom returns the custom namespace, keyed by prefix, for use with Write.
+func (m Mapping) Custom() map[string]string {
+	return map[string]string{m.Namespace.Prefix: m.Namespace.URI}
+}
+
+// Property returns the property a view field is written to.
+func (m Mapping) Property(field string, values ...string) Property {
+	if qname, ok := m.Fields[field]; ok {
+		prefix, name, _ := strings.Cut(qname, ":")
+		return NewProperty(prefix, name, values...)
+	}
+
+	return NewProperty(m.Namespace.Prefix, field, values...)
+}
+
+// Field returns the view field a property read from a packet maps to, if any.
+func (m Mapping) Field(p ReadProperty) (string, bool) {
+	for field, qname := range m.Fields {
+		prefix, name, _ := strings.Cut(qname, ":")
+		if name == p.Name && m.uri(prefix) == p.Space {
+			return field, true
+		}
+	}
+
+	if p.Space == m.Namespace.URI {
+		return p.Name, true
+	}
+
+	return "", false
+}
+
+// Is reports whether a property read from a packet is the given prefixed property.
+func (m Mapping) Is(p ReadProperty, prefix, name string) bool {
+	return p.Name == name && p.Space == m.uri(prefix)
+}
+
+func (m Mapping) uri(prefix string) string {
+	if prefix == m.Namespace.Prefix {
+		return m.Namespace.URI
+	}
+	return Namespaces[prefix]
+}
@@ -0,0 +1,280 @@
+/*
+Package xmp provides a reader and writer for XMP sidecar packets, and the mapping
+between view fields and XMP properties.
+*/
+package xmp
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsX   = "adobe:ns:meta/"
+	nsXML = "http://www.w3.org/XML/1998/namespace"
+
+	// KindSimple is a property holding a single value.
+	KindSimple = "simple"
+	// KindBag is a property holding an unordered list of values.
+	KindBag = "Bag"
+	// KindSeq is a property holding an ordered list of values.
+	KindSeq = "Seq"
+	// KindAlt is a property holding alternative values, such as translations.
+	KindAlt = "Alt"
+)
+
+// Namespaces are the standard XMP namespaces which view fields can be mapped to, keyed by prefix.
+var Namespaces = map[string]string{
+	"dc":           "http://purl.org/dc/elements/1.1/",
+	"xmp":          "http://ns.adobe.com/xap/1.0/",
+	"xmpRights":    "http://ns.adobe.com/xap/1.0/rights/",
+	"xmpDM":        "http://ns.adobe.com/xmp/1.0/DynamicMedia/",
+	"photoshop":    "http://ns.adobe.com/photoshop/1.0/",
+	"Iptc4xmpCore": "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/",
+}
+
+// kinds are the array types of the standard properties which aren't simple values.
+var kinds = map[string]string{
+	"dc:contributor":                   KindBag,
+	"dc:creator":                       KindSeq,
+	"dc:date":                          KindSeq,
+	"dc:description":                   KindAlt,
+	"dc:language":                      KindBag,
+	"dc:publisher":                     KindBag,
+	"dc:relation":                      KindBag,
+	"dc:rights":                        KindAlt,
+	"dc:subject":                       KindBag,
+	"dc:title":                         KindAlt,
+	"dc:type":                          KindBag,
+	"xmp:Identifier":                   KindBag,
+	"xmpRights:Owner":                  KindBag,
+	"xmpRights:UsageTerms":             KindAlt,
+	"photoshop:SupplementalCategories": KindBag,
+}
+
+// Property is a single XMP property and its values.
+type Property struct {
+	Prefix string
+	Name   string
+	Kind   string
+	Values []string
+}
+
+// QName returns the prefixed name of the property.
+func (p Property) QName() string {
+	return p.Prefix + ":" + p.Name
+}
+
+// NewProperty returns a property with the kind of the standard property of the same name.
+// Properties which aren't standard are simple if they have a single value, and bags otherwise.
+func NewProperty(prefix, name string, values ...string) Property {
+	kind, ok := kinds[prefix+":"+name]
+	if !ok {
+		kind = KindSimple
+		if len(values) > 1 {
+			kind = KindBag
+		}
+	}
+
+	return Property{
+		Prefix: prefix,
+		Name:   name,
+		Kind:   kind,
+		Values: values,
+	}
+}
+
+// Write writes an XMP packet holding the properties to w. The namespaces of any prefixes
+// which aren't standard must be given in custom.
+func Write(w io.Writer, props []Property, custom map[string]string) error {
+	bw := bufio.NewWriter(w)
+
+	used := make(map[string]string)
+	for _, p := range props {
+		uri, ok := custom[p.Prefix]
+		if !ok {
+			uri, ok = Namespaces[p.Prefix]
+		}
+		if !ok {
+			return fmt.Errorf("unknown XMP namespace prefix %s", p.Prefix)
+		}
+		used[p.Prefix] = uri
+	}
+
+	prefixes := make([]string, 0, len(used))
+	for prefix := range used {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	bw.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	bw.WriteString(`<x:xmpmeta xmlns:x="` + nsX + `">` + "\n")
+	bw.WriteString(` <rdf:RDF xmlns:rdf="` + nsRDF + `">` + "\n")
+	bw.WriteString(`  <rdf:Description rdf:about=""`)
+	for _, prefix := range prefixes {
+		fmt.Fprintf(bw, "\n    xmlns:%s=\"%s\"", prefix, escape(used[prefix]))
+	}
+	bw.WriteString(">\n")
+
+	for _, p := range props {
+		if len(p.Values) == 0 {
+			continue
+		}
+
+		if p.Kind == KindSimple {
+			fmt.Fprintf(bw, "   <%s>%s</%s>\n", p.QName(), escape(p.Values[0]), p.QName())
+			continue
+		}
+
+		fmt.Fprintf(bw, "   <%s>\n    <rdf:%s>\n", p.QName(), p.Kind)
+		for _, v := range p.Values {
+			if p.Kind == KindAlt {
+				fmt.Fprintf(bw, "     <rdf:li xml:lang=\"x-default\">%s</rdf:li>\n", escape(v))
+				continue
+			}
+			fmt.Fprintf(bw, "     <rdf:li>%s</rdf:li>\n", escape(v))
+		}
+		fmt.Fprintf(bw, "    </rdf:%s>\n   </%s>\n", p.Kind, p.QName())
+	}
+
+	bw.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n")
+	bw.WriteString("<?xpacket end=\"w\"?>\n")
+
+	return bw.Flush()
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// ReadProperty is a property read from an XMP packet, identified by its namespace URI.
+type ReadProperty struct {
+	Space  string
+	Name   string
+	Values []string
+}
+
+// Read reads the properties of every rdf:Description in an XMP packet. Properties written
+// in attribute shorthand are read as well as those written as elements. Structured values
+// are not supported and are skipped.
+func Read(r io.Reader) ([]ReadProperty, error) {
+	dec := xml.NewDecoder(r)
+
+	var props []ReadProperty
+	found := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Space != nsRDF || se.Name.Local != "Description" {
+			continue
+		}
+		found = true
+
+		for _, attr := range se.Attr {
+			if attr.Name.Space == nsRDF || attr.Name.Space == "xmlns" || attr.Name.Space == "" || attr.Name.Space == nsXML {
+				continue
+			}
+			props = append(props, ReadProperty{Space: attr.Name.Space, Name: attr.Name.Local, Values: []string{attr.Value}})
+		}
+
+		descProps, err := readDescription(dec)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, descProps...)
+	}
+
+	if !found {
+		return nil, errors.New("no rdf:Description found in XMP packet")
+	}
+
+	return props, nil
+}
+
+// readDescription reads the property elements of an rdf:Description up to its end element.
+func readDescription(dec *xml.Decoder) ([]ReadProperty, error) {
+	var props []ReadProperty
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return props, nil
+		case xml.StartElement:
+			values, err := readValues(dec)
+			if err != nil {
+				return nil, err
+			}
+			if values != nil {
+				props = append(props, ReadProperty{Space: t.Name.Space, Name: t.Name.Local, Values: values})
+			}
+		}
+	}
+}
+
+// readValues reads the value of a property element, which is either its text or the
+// rdf:li items of an array. It returns nil for structured values.
+func readValues(dec *xml.Decoder) ([]string, error) {
+	var text strings.Builder
+	var values []string
+	isArray, structured := false, false
+	depth := 0
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			depth++
+			text.Reset()
+			switch {
+			case t.Name.Space == nsRDF && (t.Name.Local == KindBag || t.Name.Local == KindSeq || t.Name.Local == KindAlt):
+				isArray = true
+			case t.Name.Space == nsRDF && t.Name.Local == "li":
+			default:
+				structured = true
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				switch {
+				case structured:
+					return nil, nil
+				case isArray:
+					if values == nil {
+						values = []string{}
+					}
+					return values, nil
+				default:
+					return []string{strings.TrimSpace(text.String())}, nil
+				}
+			}
+			if t.Name.Space == nsRDF && t.Name.Local == "li" {
+				values = append(values, strings.TrimSpace(text.String()))
+			}
+			depth--
+		}
+	}
+}